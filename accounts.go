@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+// labeledHandler prefixes every message with the label of the account it
+// belongs to.
+type labeledHandler struct {
+	label string
+	inner plugin.MessageHandler
+}
+
+func (h labeledHandler) SendMessage(msg plugin.Message) error {
+	if msg.Title != "" {
+		msg.Title = fmt.Sprintf("[%s] %s", h.label, msg.Title)
+	} else {
+		msg.Message = fmt.Sprintf("[%s] %s", h.label, msg.Message)
+	}
+	return h.inner.SendMessage(msg)
+}
+
+// errDisabled is returned for messages sent after the plugin was disabled,
+// e.g. by a poll that was still running.
+var errDisabled = errors.New("plugin is disabled")
+
+// enabledHandler drops messages once the requests of its plugin are
+// cancelled by Disable.
+type enabledHandler struct {
+	plugin *MyPlugin
+	inner  plugin.MessageHandler
+}
+
+func (h enabledHandler) SendMessage(msg plugin.Message) error {
+	if h.plugin.requestContext().Err() != nil {
+		return errDisabled
+	}
+	if err := h.inner.SendMessage(msg); err != nil {
+		return err
+	}
+	h.plugin.mu.Lock()
+	h.plugin.messagesSent++
+	h.plugin.mu.Unlock()
+	return nil
+}
+
+func parseAPIBaseURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("apiBaseURL %q must be an absolute http(s) URL", raw)
+	}
+	return strings.TrimRight(raw, "/"), nil
+}
+
+// buildAccounts creates one instance per entry of conf.Accounts. Every
+// account starts from the main configuration and may override any option,
+// except its credentials: label and either a token or a GitHub App are
+// required. Accounts are polled independently with their
+// own seen state, rate limit window and backoff.
+func (c *MyPlugin) buildAccounts(conf Config) ([]*MyPlugin, error) {
+	overrides := conf.Accounts
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+
+	base := conf
+	base.Label = ""
+	base.Accounts = nil
+	base.ConfigFile = ""
+	base.WatchConfigFile = false
+	b, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*MyPlugin, 0, len(overrides))
+	labels := map[string]bool{conf.Label: true}
+	for i, override := range overrides {
+		var merged map[string]interface{}
+		if err := json.Unmarshal(b, &merged); err != nil {
+			return nil, err
+		}
+		for _, k := range []string{"token", "githubAppID", "githubInstallationID", "githubAppPrivateKey"} {
+			delete(merged, k)
+		}
+		for k, v := range override {
+			merged[k] = v
+		}
+		delete(merged, "accounts")
+		delete(merged, "configFile")
+		delete(merged, "watchConfigFile")
+
+		if token, _ := override["token"].(string); token == "" && override["githubAppID"] == nil {
+			return nil, fmt.Errorf("account %d: token is required unless a GitHub App is set", i+1)
+		}
+		label, _ := override["label"].(string)
+		if label == "" || labels[label] {
+			return nil, fmt.Errorf("account %d: a unique label is required", i+1)
+		}
+		labels[label] = true
+
+		account := NewGotifyPluginInstance(c.ctx).(*MyPlugin)
+		account.clock = c.clock
+		if err := account.ValidateAndSetConfig(merged); err != nil {
+			return nil, fmt.Errorf("account %s: %w", label, err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// reuseAccounts swaps each freshly validated account for the running one
+// with the same label, reconfigured in place, so a reload keeps its state.
+func (c *MyPlugin) reuseAccounts(accounts []*MyPlugin) ([]*MyPlugin, error) {
+	running := make(map[string]*MyPlugin, len(c.accounts))
+	for _, account := range c.accounts {
+		running[account.label] = account
+	}
+	for i, account := range accounts {
+		existing, ok := running[account.label]
+		if !ok {
+			continue
+		}
+		if err := existing.setConfig(account.inlineConfig, true); err != nil {
+			return nil, fmt.Errorf("account %s: %w", account.label, err)
+		}
+		accounts[i] = existing
+	}
+	return accounts, nil
+}
+
+// replaceAccounts swaps in a new set of accounts, restarting polling when the
+// plugin is running. Accounts in both sets keep polling.
+func (c *MyPlugin) replaceAccounts(accounts []*MyPlugin) {
+	previous := make(map[*MyPlugin]bool, len(c.accounts))
+	for _, account := range c.accounts {
+		previous[account] = true
+	}
+	next := make(map[*MyPlugin]bool, len(accounts))
+	for _, account := range accounts {
+		next[account] = true
+	}
+	if c.enabled {
+		for _, account := range c.accounts {
+			if !next[account] {
+				account.Disable()
+			}
+		}
+	}
+	c.accounts = accounts
+	c.applyMessageHandler()
+	if c.enabled {
+		for _, account := range c.accounts {
+			if !previous[account] {
+				account.Enable()
+			}
+		}
+	}
+}
+
+// limitAndLabel wraps h in the send limit and the account label.
+func (c *MyPlugin) limitAndLabel(h plugin.MessageHandler) plugin.MessageHandler {
+	if c.sendLimiter != nil {
+		h = limitedHandler{limiter: c.sendLimiter, inner: h}
+	}
+	if c.label != "" {
+		h = labeledHandler{label: c.label, inner: h}
+	}
+	return h
+}
+
+func (c *MyPlugin) applyMessageHandler() {
+	base := c.rawHandler
+	if base != nil && c.appToken != "" {
+		base = c.newAppTokenHandler()
+	}
+	c.msgHandler, c.vipHandler = nil, nil
+	held := c.quietHandler.take()
+	c.quietHandler = nil
+	if base != nil {
+		c.vipHandler = c.limitAndLabel(enabledHandler{plugin: c, inner: base})
+		c.msgHandler = c.vipHandler
+		if c.quietEnabled {
+			c.quietHandler = &quietHandler{plugin: c, window: c.quiet, inner: c.vipHandler, queue: held}
+			c.msgHandler = c.quietHandler
+			held = nil
+		}
+	}
+	// Messages held under quiet hours that are no longer configured are
+	// delivered right away.
+	if c.msgHandler != nil {
+		c.deliverHeld(held, c.msgHandler)
+	}
+	for _, account := range c.accounts {
+		account.rawHandler = c.rawHandler
+		account.sendLimiter = c.sendLimiter
+		account.store = c.store
+		account.storageKey = account.label
+		account.applyMessageHandler()
+	}
+}
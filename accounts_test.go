@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountsArePolledIndependentlyAndLabeled(t *testing.T) {
+	work := newFixtureServer(t)
+	work.serveFixture("/notifications", "notifications_initial.json")
+	enterprise := newFixtureServer(t)
+	enterprise.serveFixture("/notifications", "notifications_initial.json")
+
+	p, rec := newTestPlugin(t, work, map[string]interface{}{
+		"label": "github",
+		"accounts": []map[string]interface{}{
+			{"label": "corp", "token": "corp-token", "apiBaseURL": enterprise.URL + "/"},
+		},
+	})
+	require.Len(t, p.accounts, 1)
+	corp := p.accounts[0]
+	assert.Equal(t, enterprise.URL, corp.baseURL)
+	assert.Equal(t, "corp-token", corp.githubToken)
+
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	work.serveFixture("/notifications", "notifications.json")
+	enterprise.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	corp.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2, "seen state is kept per account")
+	assert.Equal(t, "[github] [PR] Fix the thing", msgs[0].Title)
+	assert.Equal(t, "[corp] [PR] Fix the thing", msgs[1].Title)
+}
+
+func TestAccountValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+
+	err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "accounts": []map[string]interface{}{{"label": "corp"}}})
+	assert.ErrorContains(t, err, "token is required")
+
+	err = p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "accounts": []map[string]interface{}{{"token": "y"}}})
+	assert.ErrorContains(t, err, "unique label")
+
+	err = p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "accounts": []map[string]interface{}{
+		{"label": "corp", "token": "y", "apiBaseURL": "not a url"},
+	}})
+	assert.ErrorContains(t, err, "apiBaseURL")
+
+	err = p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "accounts": []map[string]interface{}{
+		{"label": "org", "githubAppID": 7, "githubInstallationID": 42, "githubAppPrivateKey": "not a key"},
+	}})
+	assert.ErrorContains(t, err, "githubAppPrivateKey", "the main token is not inherited by an app account")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+// Gotify application tokens are an "A" followed by 14 random characters.
+// Client tokens look the same but start with a "C".
+var appTokenPattern = regexp.MustCompile(`^A[A-Za-z0-9._-]{14}$`)
+
+// validateAppToken reports an apptoken that Gotify cannot have issued to an
+// application. An empty token selects the plugin's own application.
+func validateAppToken(token string) error {
+	switch {
+	case token == "":
+		return nil
+	case strings.HasPrefix(token, "C") && len(token) == 15:
+		return fmt.Errorf("apptoken is a client token, create an application and use its token")
+	case !appTokenPattern.MatchString(token):
+		return fmt.Errorf("apptoken must be a Gotify application token: 15 characters starting with A")
+	}
+	return nil
+}
+
+func parseGotifyURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("gotifyURL %q must be an absolute http(s) URL", raw)
+	}
+	return strings.TrimRight(raw, "/"), nil
+}
+
+// appTokenHandler posts messages to Gotify's REST API with an application
+// token, so they belong to that application rather than to the plugin's.
+type appTokenHandler struct {
+	plugin *MyPlugin
+	client *http.Client
+	url    string
+	token  string
+}
+
+func (c *MyPlugin) newAppTokenHandler() *appTokenHandler {
+	return &appTokenHandler{
+		plugin: c,
+		client: &http.Client{Timeout: c.requestTimeout},
+		url:    c.gotifyURL + "/message",
+		token:  c.appToken,
+	}
+}
+
+type gotifyMessage struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Priority int                    `json:"priority"`
+	Extras   map[string]interface{} `json:"extras,omitempty"`
+}
+
+func (h *appTokenHandler) SendMessage(msg plugin.Message) error {
+	body, err := json.Marshal(gotifyMessage{
+		Title:    msg.Title,
+		Message:  msg.Message,
+		Priority: msg.Priority,
+		Extras:   msg.Extras,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(h.plugin.requestContext(), http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", h.token)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("gotify responded %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
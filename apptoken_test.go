@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppTokenValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+
+	assert.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apptoken": "AbCdEfG.h-_1234"}))
+	assert.Equal(t, "AbCdEfG.h-_1234", p.appToken)
+
+	assert.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apptoken": ""}))
+	assert.Empty(t, p.appToken, "an empty token uses the plugin's application")
+
+	for _, token := range []string{"app-secret", "AbCdEfGh", "AbCdEfG.h-_12345", "AbCdEfG h-_1234"} {
+		assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apptoken": token}), token)
+	}
+	err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apptoken": "CbCdEfG.h-_1234"})
+	assert.ErrorContains(t, err, "client token")
+
+	err = p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apptoken": "AbCdEfG.h-_1234", "gotifyURL": "localhost:80"})
+	assert.ErrorContains(t, err, "gotifyURL")
+}
+
+func TestAppTokenRoutesMessagesThroughGotifyAPI(t *testing.T) {
+	var mu sync.Mutex
+	var received []gotifyMessage
+	gotify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/message" || r.Header.Get("X-Gotify-Key") != "AbCdEfG.h-_1234" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var msg gotifyMessage
+		if !assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg)) {
+			return
+		}
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer gotify.Close()
+
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"apptoken": "AbCdEfG.h-_1234", "gotifyURL": gotify.URL + "/"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Routed")+`]`))
+	p.checkNotifications()
+
+	assert.Empty(t, rec.Messages(), "the plugin's own application is bypassed")
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 1)
+	assert.Equal(t, "[Issue] Routed", received[0].Title)
+	assert.Contains(t, received[0].Extras, "client::notification")
+	assert.Nil(t, p.lastError)
+}
+
+func TestAppTokenReportsRejectedMessages(t *testing.T) {
+	gotify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer gotify.Close()
+
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"apptoken": "AbCdEfG.h-_1234", "gotifyURL": gotify.URL})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	err := p.msgHandler.SendMessage(plugin.Message{Title: "t", Message: "m"})
+	assert.ErrorContains(t, err, "401 Unauthorized")
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+	timers  []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time, 1), interval: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires every ticker and timer that
+// became due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	tickers := append([]*fakeTicker(nil), c.tickers...)
+	var pending []fakeTimer
+	for _, t := range c.timers {
+		if now.Before(t.at) {
+			pending = append(pending, t)
+		} else {
+			t.c <- now
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+	for _, t := range tickers {
+		t.fire(now)
+	}
+}
+
+func (c *fakeClock) timerCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func (c *fakeClock) tickerCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.tickers)
+}
+
+type fakeTicker struct {
+	mu       sync.Mutex
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next = t.next.Add(d - t.interval)
+	t.interval = d
+	t.stopped = false
+}
+
+func (t *fakeTicker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+}
+
+func (t *fakeTicker) fire(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped || now.Before(t.next) {
+		return
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.interval)
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func TestFakeClockDrivesPolling(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+
+	srv.serveFixture("/notifications", "notifications.json")
+	clk.Advance(59 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, rec.Messages())
+
+	clk.Advance(time.Second)
+	assert.Eventually(t, func() bool { return len(rec.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestFakeClockRepoGap(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"repoMinGap": 10})
+	clk := newFakeClock()
+	p.clock = clk
+	p.repoLastSent = map[string]time.Time{}
+	p.repoSuppressed = map[string]heldBack{}
+
+	p.markRepoSent("octocat/hello-world")
+	clk.Advance(9 * time.Minute)
+	assert.False(t, p.allowRepoMessage("octocat/hello-world", 2))
+	clk.Advance(time.Minute)
+	assert.True(t, p.allowRepoMessage("octocat/hello-world", 2))
+}
+
+func TestIntervalChangeAppliesWhileRunning(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 3600})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, p.ApplyConfig(map[string]interface{}{"token": "test-token", "apiBaseURL": srv.URL, "interval": 20}))
+	require.Eventually(t, func() bool { return clk.tickerInterval() == 20*time.Second }, time.Second, time.Millisecond)
+
+	srv.serveFixture("/notifications", "notifications.json")
+	clk.Advance(20 * time.Second)
+	assert.Eventually(t, func() bool { return len(rec.Messages()) == 1 }, time.Second, 10*time.Millisecond,
+		"the next tick uses the new interval")
+}
+
+func TestRejectedConfigKeepsTheRunningInterval(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 3600})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+
+	err := p.ApplyConfig(map[string]interface{}{"token": "test-token", "apiBaseURL": srv.URL, "interval": 20, "maxPages": 0})
+	require.Error(t, err)
+	assert.Equal(t, time.Hour, p.pollInterval, "a rejected config leaves the interval alone")
+	assert.Never(t, func() bool { return clk.tickerInterval() != time.Hour }, 50*time.Millisecond, time.Millisecond,
+		"the poller is not woken for a rejected config")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gotify/plugin-api"
+)
+
+type collaborator struct {
+	Login    string `json:"login"`
+	RoleName string `json:"role_name"`
+}
+
+// scanCollaborators compares the collaborators of every watched repo with the
+// last snapshot and reports additions, removals and permission changes. The
+// snapshots are persisted, so changes made while the plugin was disabled are
+// reported on the next start. A repo seen for the first time is only
+// recorded. Repos the token may not list collaborators of are skipped until
+// the plugin is re-enabled.
+func (c *MyPlugin) scanCollaborators() {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for collaborator watch", err)
+		return
+	}
+
+	changed := false
+	for _, repo := range repos {
+		if c.collaboratorsForbidden[repo.FullName] {
+			continue
+		}
+		endpoint := fmt.Sprintf("%s/repos/%s/collaborators?per_page=100", c.baseURL, repo.FullName)
+		list, err := fetchAllPages[collaborator](c, endpoint, "application/vnd.github.v3+json")
+		if err != nil {
+			if classifyError(err).StatusCode == http.StatusForbidden {
+				c.infoLog("skipping collaborators, the token needs write or admin access", "repo", repo.FullName)
+				c.collaboratorsForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching collaborators of %s", repo.FullName), err)
+			}
+			continue
+		}
+
+		current := make(map[string]string, len(list))
+		for _, collab := range list {
+			current[collab.Login] = collab.RoleName
+		}
+		previous, known := c.collaborators[repo.FullName]
+		c.collaborators[repo.FullName] = current
+		changed = true
+		if !known {
+			continue
+		}
+
+		for _, login := range sortedKeys(current) {
+			role, existed := previous[login]
+			switch {
+			case !existed:
+				c.sendCollaboratorMessage(repo.FullName, fmt.Sprintf("%s added as collaborator to %s", login, repo.FullName),
+					fmt.Sprintf("Permission: %s", current[login]))
+			case role != current[login]:
+				c.sendCollaboratorMessage(repo.FullName, fmt.Sprintf("%s permission changed in %s", login, repo.FullName),
+					fmt.Sprintf("Permission: %s → %s", role, current[login]))
+			}
+		}
+		for _, login := range sortedKeys(previous) {
+			if _, ok := current[login]; !ok {
+				c.sendCollaboratorMessage(repo.FullName, fmt.Sprintf("%s removed as collaborator from %s", login, repo.FullName),
+					fmt.Sprintf("Permission was: %s", previous[login]))
+			}
+		}
+	}
+	if changed {
+		c.saveState()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *MyPlugin) sendCollaboratorMessage(repo, title, message string) {
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  message,
+		Priority: 5,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": c.webURL(repo + "/settings/access"),
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending collaborator notification", err)
+	} else {
+		c.infoLog("sent collaborator notification", "title", title)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollaboratorChangesAgainstPersistedSnapshot(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	srv.serveFixture("/user/repos", "user_repos.json")
+	srv.handle("/repos/octocat/hello-world/collaborators", serveJSON(`[
+		{"login": "alice", "role_name": "admin"},
+		{"login": "carol", "role_name": "read"}
+	]`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchCollaborators": true})
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.store.put("", accountState{Collaborators: map[string]map[string]string{
+		"octocat/hello-world": {"alice": "write", "bob": "admin"},
+	}}))
+
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "alice permission changed in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "Permission: write → admin", msgs[0].Message)
+	assert.Equal(t, "carol added as collaborator to octocat/hello-world", msgs[1].Title)
+	assert.Equal(t, "Permission: read", msgs[1].Message)
+	assert.Equal(t, "bob removed as collaborator from octocat/hello-world", msgs[2].Title)
+
+	assert.Equal(t, map[string]string{"alice": "admin", "carol": "read"},
+		p.loadState().Collaborators["octocat/hello-world"], "the new snapshot is persisted")
+
+	p.scanCollaborators()
+	assert.Len(t, rec.Messages(), 3)
+}
+
+func TestCollaboratorsForbiddenRepoIsSkipped(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/user/repos", "user_repos.json")
+	requests := 0
+	srv.handle("/repos/octocat/hello-world/collaborators", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, `{"message":"Must have push access to view repository collaborators."}`, http.StatusForbidden)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchCollaborators": true})
+	p.collaborators = map[string]map[string]string{}
+	p.collaboratorsForbidden = map[string]bool{}
+
+	p.scanCollaborators()
+	p.scanCollaborators()
+	assert.Equal(t, 1, requests)
+	assert.Empty(t, rec.Messages())
+}
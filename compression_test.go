@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCompressed(encoding, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		var zw io.WriteCloser
+		if encoding == "gzip" {
+			zw = gzip.NewWriter(&buf)
+		} else {
+			zw = zlib.NewWriter(&buf)
+		}
+		zw.Write([]byte(body))
+		zw.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(buf.Bytes())
+	}
+}
+
+func TestCompressedResponsesAreDecoded(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		var acceptEncodings []string
+		srv := newFixtureServer(t)
+		srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+			acceptEncodings = append(acceptEncodings, r.Header.Get("Accept-Encoding"))
+			serveCompressed(encoding, `[`+notificationJSON("1", "Compressed")+`]`)(w, r)
+		})
+		p, _ := newTestPlugin(t, srv, nil)
+		// The transport's own gzip handling must not be needed.
+		p.client.Transport.(*http.Transport).DisableCompression = true
+
+		notifications, _, err := p.fetchNotifications()
+		require.NoError(t, err, encoding)
+		require.Len(t, notifications, 1, encoding)
+		assert.Equal(t, "Compressed", notifications[0].Subject.Title, encoding)
+		assert.Equal(t, []string{"gzip, deflate"}, acceptEncodings, encoding)
+	}
+}
+
+func TestNotModifiedWithContentEncodingIsNotAnError(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusNotModified)
+	})
+	p, _ := newTestPlugin(t, srv, nil)
+	p.notificationsETag = `"abc"`
+
+	_, changed, err := p.fetchNotifications()
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// configFileDir holds the config files, relative to Gotify's working
+// directory next to its own data. Tests point it at a temporary directory.
+var configFileDir = filepath.Join("data", "github-plugin")
+
+// configFilePath resolves name inside configFileDir, refusing absolute paths
+// and paths that climb out of it.
+func configFilePath(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("configFile %q must be a relative path inside %s", name, configFileDir)
+	}
+	return filepath.Join(configFileDir, name), nil
+}
+
+// mergeConfigFile overlays the JSON document named by conf.ConfigFile onto
+// conf. Keys missing from the file keep their inline value. The file cannot
+// point to another file.
+func mergeConfigFile(conf *Config) (time.Time, error) {
+	name := conf.ConfigFile
+	path, err := configFilePath(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading config file: %w", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading config file: %w", err)
+	}
+	if err := json.Unmarshal(b, conf); err != nil {
+		return time.Time{}, fmt.Errorf("parsing config file %s: %w", name, err)
+	}
+	conf.ConfigFile = name
+	return info.ModTime(), nil
+}
+
+// reloadConfigFileIfChanged applies the config file again once it changed.
+// Accounts and the connection state carry over unless the credentials
+// changed.
+func (c *MyPlugin) reloadConfigFileIfChanged() {
+	c.pollMu.Lock()
+	name, modTime, inline := c.configFile, c.configFileModTime, c.inlineConfig
+	c.pollMu.Unlock()
+	if name == "" {
+		return
+	}
+	path, err := configFilePath(name)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		c.recordError(fmt.Sprintf("checking config file %s", path), err)
+		return
+	}
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+	if err := c.setConfig(inline, true); err != nil {
+		c.recordError(fmt.Sprintf("reloading config file %s", path), err)
+		c.pollMu.Lock()
+		c.configFileModTime = info.ModTime()
+		c.pollMu.Unlock()
+		return
+	}
+	c.infoLog("reloaded config file", "path", path)
+}
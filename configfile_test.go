@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfigFileDir points configFileDir at a temporary directory and returns
+// it.
+func useConfigFileDir(t *testing.T) string {
+	dir := t.TempDir()
+	previous := configFileDir
+	configFileDir = dir
+	t.Cleanup(func() { configFileDir = previous })
+	return dir
+}
+
+// touchLater writes content to path with a modification time in the future,
+// so the next reload sees the change.
+func touchLater(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+}
+
+func TestConfigFileOverridesInlineConfig(t *testing.T) {
+	path := filepath.Join(useConfigFileDir(t), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"watchStars": true, "unknownTypePriority": 5}`), 0o600))
+
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{
+		"token":               "inline-token",
+		"interval":            60,
+		"unknownTypePriority": 2,
+		"configFile":          "config.json",
+	}))
+	assert.Equal(t, "inline-token", p.githubToken)
+	assert.True(t, p.watchStars)
+	assert.Equal(t, 5, p.unknownTypePriority)
+
+	touchLater(t, path, `{"unknownTypePriority": 7}`)
+	p.reloadConfigFileIfChanged()
+	assert.Equal(t, 7, p.unknownTypePriority)
+	assert.False(t, p.watchStars)
+
+	touchLater(t, path, `{"unknownTypePriority": 7, "interval": 1}`)
+	p.reloadConfigFileIfChanged()
+	assert.Equal(t, 60*time.Second, p.pollInterval, "an invalid file leaves the running config alone")
+	errs := p.getRecentErrors()
+	require.NotEmpty(t, errs)
+	assert.Equal(t, "reloading config file "+path, errs[0].Op)
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	dir := useConfigFileDir(t)
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "configFile": "missing.json"})
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{not json`), 0o600))
+	err = p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "configFile": "config.json"})
+	assert.Error(t, err)
+}
+
+func TestConfigFileStaysInsideTheDataDirectory(t *testing.T) {
+	dir := useConfigFileDir(t)
+	outside := filepath.Join(filepath.Dir(dir), "outside.json")
+	require.NoError(t, os.WriteFile(outside, []byte(`{}`), 0o600))
+	t.Cleanup(func() { os.Remove(outside) })
+
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	for _, name := range []string{outside, "../outside.json", "nested/../../outside.json"} {
+		err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "configFile": name})
+		assert.ErrorContains(t, err, "must be a relative path", name)
+	}
+}
+
+func TestConfigFileReloadKeepsAccountsAndConnectionState(t *testing.T) {
+	path := filepath.Join(useConfigFileDir(t), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"unknownTypePriority": 5}`), 0o600))
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{
+		"token":      "x",
+		"interval":   60,
+		"configFile": "config.json",
+		"accounts":   []map[string]interface{}{{"label": "corp", "token": "y"}},
+	}))
+	corp := p.accounts[0]
+	p.mu.Lock()
+	p.pausedForAuth = true
+	p.user = authUser{Login: "octocat"}
+	p.mu.Unlock()
+
+	touchLater(t, path, `{"unknownTypePriority": 7}`)
+	p.reloadConfigFileIfChanged()
+	assert.Equal(t, 7, p.unknownTypePriority)
+	require.Len(t, p.accounts, 1)
+	assert.Same(t, corp, p.accounts[0], "the account is reconfigured in place")
+	assert.Equal(t, 7, corp.unknownTypePriority)
+	assert.True(t, p.pausedForAuth, "the connection state carries over")
+	assert.Equal(t, "octocat", p.user.Login)
+
+	touchLater(t, path, `{"token": "rotated"}`)
+	p.reloadConfigFileIfChanged()
+	assert.Equal(t, "rotated", p.githubToken)
+	assert.False(t, p.pausedForAuth, "new credentials start over")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/gotify/plugin-api"
+)
+
+// sendLimiter bounds how many SendMessage calls run at the same time. The
+// main instance and all extra accounts share one limiter, so the cap applies
+// to everything the plugin sends to the Gotify server.
+//
+// With a limit of 1 messages reach Gotify in the order they were produced.
+// With a higher limit messages produced concurrently, e.g. by two accounts
+// polling at the same time, may arrive in any order; messages produced by a
+// single poll still arrive in order because each poll sends one at a time.
+type sendLimiter chan struct{}
+
+func newSendLimiter(limit int) sendLimiter {
+	return make(sendLimiter, limit)
+}
+
+type limitedHandler struct {
+	limiter sendLimiter
+	inner   plugin.MessageHandler
+}
+
+func (h limitedHandler) SendMessage(msg plugin.Message) error {
+	h.limiter <- struct{}{}
+	defer func() { <-h.limiter }()
+	return h.inner.SendMessage(msg)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowHandler struct {
+	running, peak atomic.Int32
+}
+
+func (h *slowHandler) SendMessage(plugin.Message) error {
+	n := h.running.Add(1)
+	for {
+		peak := h.peak.Load()
+		if n <= peak || h.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	h.running.Add(-1)
+	return nil
+}
+
+func TestLimitedHandlerCapsConcurrentSends(t *testing.T) {
+	for _, limit := range []int{1, 3} {
+		inner := &slowHandler{}
+		h := limitedHandler{limiter: newSendLimiter(limit), inner: inner}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.SendMessage(plugin.Message{})
+			}()
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, inner.peak.Load(), int32(limit))
+	}
+}
+
+func TestAccountsShareSendLimiter(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{
+		"maxConcurrentSends": 2,
+		"accounts":           []map[string]interface{}{{"label": "work", "token": "work-token"}},
+	})
+	require.Len(t, p.accounts, 1)
+	assert.Equal(t, 2, cap(p.sendLimiter))
+	assert.Equal(t, p.sendLimiter, p.accounts[0].sendLimiter)
+
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "maxConcurrentSends": 0}))
+}
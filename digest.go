@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+// pendingNotification is a notification that passed all filters in this
+// poll and is waiting to be sent.
+type pendingNotification struct {
+	notification GithubNotification
+	typeLabel    string
+	priority     int
+	details      []string
+	// vip notifications skip quiet hours and are never part of a digest.
+	vip bool
+}
+
+// sendDigest sends pending as a single Markdown message listing each
+// notification, at the highest of their priorities.
+func (c *MyPlugin) sendDigest(pending []pendingNotification) {
+	repos := make(map[string]bool)
+	priority := 0
+	var b strings.Builder
+	for _, p := range pending {
+		repo := p.notification.Repository.FullName
+		repos[repo] = true
+		priority = max(priority, p.priority)
+		title, _ := c.formatNotification(p.notification, p.typeLabel)
+		fmt.Fprintf(&b, "- [%s](%s) in %s\n",
+			markdownEscaper.Replace(title), c.subjectWebURL(p.notification), markdownEscaper.Replace(repo))
+	}
+	msg := plugin.Message{
+		Title:    c.translate("digest.title", len(pending), len(repos)),
+		Message:  strings.TrimSuffix(b.String(), "\n"),
+		Priority: priority,
+		Extras: map[string]interface{}{
+			"client::display": map[string]interface{}{
+				"contentType": "text/markdown",
+			},
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": c.webURL("notifications"),
+				},
+			},
+		},
+	}
+	notifications := make([]GithubNotification, len(pending))
+	for i, p := range pending {
+		notifications[i] = p.notification
+	}
+	held, err := c.deliver(msg, false, notifications...)
+	switch {
+	case err != nil:
+		c.recordError("sending notification digest", err)
+	case held:
+		c.infoLog("holding notification digest for quiet hours", "count", len(pending))
+	default:
+		c.infoLog("sent notification digest", "count", len(pending))
+	}
+}
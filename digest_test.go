@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digestNotificationsJSON(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		repo := "octocat/hello-world"
+		if i%2 == 1 {
+			repo = "octocat/spoon-knife"
+		}
+		items[i] = fmt.Sprintf(`{"id":"%d","repository":{"full_name":%q},`+
+			`"subject":{"title":"T%d","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}`, i+1, repo, i+1)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestDigestReplacesBurstOfNotifications(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"digestMode": true, "digestThreshold": 3})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(digestNotificationsJSON(5)))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "5 new notifications across 2 repos", msgs[0].Title)
+	lines := strings.Split(msgs[0].Message, "\n")
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], `- [\[Issue\] T1](`), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ") in octocat/spoon-knife"), lines[1])
+	assert.Equal(t, "text/markdown", msgs[0].Extras["client::display"].(map[string]interface{})["contentType"])
+}
+
+func TestDigestKeepsSmallCountsIndividual(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"digestMode": true, "digestThreshold": 3})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(digestNotificationsJSON(3)))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "[Issue] T1", msgs[0].Title)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+const discussionQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 20, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      nodes {
+        title
+        url
+        answer { id url bodyText author { login } }
+        comments(last: 1) { nodes { id url bodyText author { login } } }
+      }
+    }
+  }
+}`
+
+type discussionComment struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	BodyText string `json:"bodyText"`
+	Author   struct {
+		Login string `json:"login"`
+	} `json:"author"`
+}
+
+type discussionActivity struct {
+	URL     string
+	Answer  *discussionComment
+	Comment *discussionComment
+}
+
+// discussionState remembers the newest comment and answer already reported
+// for a discussion thread.
+type discussionState struct {
+	commentID string
+	answerID  string
+}
+
+// fetchDiscussionActivity finds the discussion behind n among the recently
+// updated discussions of its repository. Discussion notifications carry no
+// API URL, so the discussion is matched by title.
+func (c *MyPlugin) fetchDiscussionActivity(n GithubNotification) (*discussionActivity, error) {
+	owner, name, ok := strings.Cut(n.Repository.FullName, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository %q", n.Repository.FullName)
+	}
+	var data struct {
+		Repository struct {
+			Discussions struct {
+				Nodes []struct {
+					Title    string             `json:"title"`
+					URL      string             `json:"url"`
+					Answer   *discussionComment `json:"answer"`
+					Comments struct {
+						Nodes []discussionComment `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+			} `json:"discussions"`
+		} `json:"repository"`
+	}
+	if err := c.graphQL(discussionQuery, map[string]interface{}{"owner": owner, "name": name}, &data); err != nil {
+		return nil, err
+	}
+	for _, d := range data.Repository.Discussions.Nodes {
+		if d.Title != n.Subject.Title {
+			continue
+		}
+		activity := &discussionActivity{URL: d.URL, Answer: d.Answer}
+		if len(d.Comments.Nodes) > 0 {
+			activity.Comment = &d.Comments.Nodes[0]
+		}
+		return activity, nil
+	}
+	return nil, nil
+}
+
+func snippet(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 140 {
+		return string(r[:139]) + "…"
+	}
+	return s
+}
+
+// newDiscussionActivity returns a line for every comment or answer that was
+// not reported before and records it. With record set, the current state is
+// only remembered, e.g. when a thread is seen for the first time.
+func (c *MyPlugin) newDiscussionActivity(n GithubNotification, record bool) (lines []string, url string) {
+	activity, err := c.fetchDiscussionActivity(n)
+	if err != nil {
+		c.recordError(fmt.Sprintf("fetching discussion activity for %s", n.ID), err)
+		return nil, ""
+	}
+	if activity == nil {
+		return nil, ""
+	}
+	state, ok := c.discussions[n.ID]
+	if !ok {
+		state = &discussionState{}
+		c.discussions[n.ID] = state
+	}
+	if a := activity.Answer; a != nil && a.ID != state.answerID {
+		state.answerID = a.ID
+		lines = append(lines, fmt.Sprintf("✅ Accepted answer by %s: %s", a.Author.Login, snippet(a.BodyText)))
+		url = a.URL
+	}
+	if cm := activity.Comment; cm != nil && cm.ID != state.commentID && cm.ID != state.answerID {
+		state.commentID = cm.ID
+		lines = append(lines, fmt.Sprintf("💬 %s: %s", cm.Author.Login, snippet(cm.BodyText)))
+		if url == "" {
+			url = cm.URL
+		}
+	}
+	if record {
+		return nil, ""
+	}
+	if url == "" {
+		url = activity.URL
+	}
+	return lines, url
+}
+
+// checkDiscussionComments reports new comments and answers on discussion
+// threads that were already notified in an earlier poll. Threads that
+// classifyNotification skips stay quiet; the others are sent at the priority
+// it returns.
+func (c *MyPlugin) checkDiscussionComments(notifications []GithubNotification, sentNow map[string]bool, filter *notificationFilter) {
+	for _, n := range notifications {
+		if n.Subject.Type != "Discussion" || sentNow[n.ID] || c.isSnoozed(n.ID) {
+			continue
+		}
+		_, known := c.discussions[n.ID]
+		lines, url := c.newDiscussionActivity(n, !known)
+		if len(lines) == 0 {
+			continue
+		}
+		priority, _, skip := c.classifyNotification(n, filter)
+		if skip != "" {
+			c.debugLog("skipping discussion activity", "id", n.ID, "reason", skip)
+			continue
+		}
+		msg := &plugin.Message{
+			Title:    c.typePrefix("Discussion", c.typeName("Discussion")) + " " + n.Subject.Title,
+			Message:  strings.Join(lines, "\n"),
+			Priority: priority,
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": url,
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(*msg); err != nil {
+			c.recordError("sending discussion comment", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discussionResponse(answer, comment string) string {
+	return fmt.Sprintf(`{"data":{"repository":{"discussions":{"nodes":[`+
+		`{"title":"Other","url":"https://github.com/octocat/hello-world/discussions/1","answer":null,"comments":{"nodes":[]}},`+
+		`{"title":"How do I configure it?","url":"https://github.com/octocat/hello-world/discussions/2","answer":%s,"comments":{"nodes":[%s]}}`+
+		`]}}}}`, answer, comment)
+}
+
+const (
+	commentC1 = `{"id":"C1","url":"https://github.com/octocat/hello-world/discussions/2#c1","bodyText":"Have you tried\nturning it off?","author":{"login":"frank"}}`
+	commentC2 = `{"id":"C2","url":"https://github.com/octocat/hello-world/discussions/2#c2","bodyText":"Set interval to 60.","author":{"login":"grace"}}`
+)
+
+func TestDiscussionComments(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	var vars map[string]interface{}
+	graphql := discussionResponse("null", commentC1)
+	srv.handle("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		vars = req.Variables
+		serveJSON(graphql)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"discussionComments": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[{"id":"7","repository":{"full_name":"octocat/hello-world"},`+
+		`"subject":{"title":"How do I configure it?","type":"Discussion","url":null}}]`))
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, map[string]interface{}{"owner": "octocat", "name": "hello-world"}, vars)
+	assert.Equal(t, "New Discussion notification in octocat/hello-world\n💬 frank: Have you tried turning it off?", msgs[0].Message)
+
+	p.checkNotifications()
+	assert.Len(t, rec.Messages(), 1, "the same comment is not reported twice")
+
+	graphql = discussionResponse(commentC2, commentC2)
+	p.checkNotifications()
+	msgs = rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "[Discussion] How do I configure it?", msgs[1].Title)
+	assert.Equal(t, "✅ Accepted answer by grace: Set interval to 60.", msgs[1].Message)
+}
+
+func TestGraphQLURL(t *testing.T) {
+	p := &MyPlugin{baseURL: "https://api.github.com"}
+	assert.Equal(t, "https://api.github.com/graphql", p.graphQLURL())
+	p.baseURL = "https://github.example.com/api/v3"
+	assert.Equal(t, "https://github.example.com/api/graphql", p.graphQLURL())
+}
+
+func TestDiscussionActivityIsClassified(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	graphql := discussionResponse("null", commentC1)
+	srv.handle("/graphql", func(w http.ResponseWriter, r *http.Request) { serveJSON(graphql)(w, r) })
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"discussionComments": true,
+		"typePriorities":     map[string]int{"Discussion": 6},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[{"id":"7","repository":{"full_name":"octocat/hello-world"},`+
+		`"subject":{"title":"How do I configure it?","type":"Discussion","url":null}}]`))
+	p.checkNotifications()
+	require.Len(t, rec.Messages(), 1)
+
+	graphql = discussionResponse("null", commentC2)
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, 6, msgs[1].Priority, "follow-ups use the type priority")
+
+	p.mu.Lock()
+	p.filterOverride = &notificationFilter{ExcludeRepos: []string{"octocat/hello-world"}}
+	p.mu.Unlock()
+	graphql = discussionResponse(commentC1, commentC2)
+	p.checkNotifications()
+	assert.Len(t, rec.Messages(), 2, "filtered threads get no follow-ups")
+}
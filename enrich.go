@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type subjectDetail struct {
+	Comments  int `json:"comments"`
+	Reactions struct {
+		TotalCount int `json:"total_count"`
+	} `json:"reactions"`
+}
+
+// fetchSubjectDetail loads the issue behind an Issue or PullRequest
+// notification. Pull requests are read through the issues endpoint because
+// only that one carries reaction counts.
+func (c *MyPlugin) fetchSubjectDetail(n GithubNotification) (*subjectDetail, error) {
+	if n.Subject.Type != "Issue" && n.Subject.Type != "PullRequest" {
+		return nil, nil
+	}
+	if n.Subject.URL == "" {
+		return nil, nil
+	}
+	endpoint := strings.Replace(n.Subject.URL, "/pulls/", "/issues/", 1)
+	var detail subjectDetail
+	if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// engagementLine renders comment and reaction totals, e.g. "💬 14 · 👍 8".
+func (c *MyPlugin) engagementLine(n GithubNotification) string {
+	detail, err := c.fetchSubjectDetail(n)
+	if err != nil {
+		c.recordError(fmt.Sprintf("fetching subject detail for %s", n.ID), err)
+		return ""
+	}
+	if detail == nil {
+		return ""
+	}
+	return fmt.Sprintf("💬 %d · 👍 %d", detail.Comments, detail.Reactions.TotalCount)
+}
+
+// pullDetail holds the state and size of a pull request. GitHub leaves the
+// counts null while it is still computing them for very large pull requests.
+type pullDetail struct {
+	State        string `json:"state"`
+	Merged       bool   `json:"merged"`
+	Additions    *int   `json:"additions"`
+	Deletions    *int   `json:"deletions"`
+	ChangedFiles *int   `json:"changed_files"`
+	updatedAt    time.Time
+	fetchedAt    time.Time
+}
+
+const pullCacheTTL = time.Hour
+
+// fetchPull loads the pull request behind a PullRequest notification. The
+// result is reused for as long as the thread was not updated, so the state
+// and the diff stat of one notification cost a single request.
+func (c *MyPlugin) fetchPull(n GithubNotification) (*pullDetail, error) {
+	now := c.clock.Now()
+	if detail, ok := c.pullCache[n.Subject.URL]; ok && detail.updatedAt.Equal(n.UpdatedAt) {
+		return detail, nil
+	}
+	var detail pullDetail
+	if err := c.getJSON(n.Subject.URL, "application/vnd.github.v3+json", &detail); err != nil {
+		return nil, err
+	}
+	for url, cached := range c.pullCache {
+		if now.Sub(cached.fetchedAt) >= pullCacheTTL {
+			delete(c.pullCache, url)
+		}
+	}
+	detail.updatedAt, detail.fetchedAt = n.UpdatedAt, now
+	c.pullCache[n.Subject.URL] = &detail
+	return &detail, nil
+}
+
+// Pull request states shown by showPullState.
+const (
+	pullOpened = "opened"
+	pullClosed = "closed"
+	pullMerged = "merged"
+)
+
+var pullStateEmoji = map[string]string{
+	pullOpened: "🟢",
+	pullClosed: "🔴",
+	pullMerged: "🟣",
+}
+
+// pullState returns whether the pull request of n is opened, closed or
+// merged, or "" if it cannot be told.
+func (c *MyPlugin) pullState(n GithubNotification) string {
+	if n.Subject.Type != "PullRequest" || n.Subject.URL == "" {
+		return ""
+	}
+	detail, err := c.fetchPull(n)
+	if err != nil {
+		c.recordError(fmt.Sprintf("fetching pull request detail for %s", n.ID), err)
+		return ""
+	}
+	switch {
+	case detail.Merged:
+		return pullMerged
+	case detail.State == "closed":
+		return pullClosed
+	case detail.State == "open":
+		return pullOpened
+	}
+	return ""
+}
+
+// pullStatePriority returns the priority typePriorities sets for a pull
+// request in state, e.g. under "PullRequest.merged", or priority if none.
+func (c *MyPlugin) pullStatePriority(state string, priority int) int {
+	key := "PullRequest." + state
+	if p, ok := c.typePriorities[key]; ok {
+		return p
+	}
+	if p, ok := defaultTypePriorities()[key]; ok {
+		return p
+	}
+	return priority
+}
+
+// withPullState prefixes title with the emoji and name of state, e.g.
+// "🟣 Merged: [PR] Fix the thing".
+func (c *MyPlugin) withPullState(title, state string) string {
+	return fmt.Sprintf("%s %s: %s", pullStateEmoji[state], c.translate("pull."+state), title)
+}
+
+// diffStatLine renders the size of a pull request, e.g. "+120 −34 · 5 files".
+func (c *MyPlugin) diffStatLine(n GithubNotification) string {
+	if n.Subject.URL == "" {
+		return ""
+	}
+	detail, err := c.fetchPull(n)
+	if err != nil {
+		c.recordError(fmt.Sprintf("fetching pull request detail for %s", n.ID), err)
+		return ""
+	}
+	if detail.Additions == nil || detail.Deletions == nil || detail.ChangedFiles == nil {
+		return ""
+	}
+	files := "files"
+	if *detail.ChangedFiles == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("+%d −%d · %d %s", *detail.Additions, *detail.Deletions, *detail.ChangedFiles, files)
+}
+
+// repoInfo is the part of a repository used for context lines. It is cached
+// for repoInfoTTL so busy repos are not fetched on every notification.
+type repoInfo struct {
+	Language        string `json:"language"`
+	StargazersCount int    `json:"stargazers_count"`
+	fetchedAt       time.Time
+}
+
+const repoInfoTTL = time.Hour
+
+func (c *MyPlugin) fetchRepoInfo(repo string) (*repoInfo, error) {
+	if info, ok := c.repoInfoCache[repo]; ok && c.clock.Now().Sub(info.fetchedAt) < repoInfoTTL {
+		return info, nil
+	}
+	var info repoInfo
+	if err := c.getJSON(fmt.Sprintf("%s/repos/%s", c.baseURL, repo), "application/vnd.github.v3+json", &info); err != nil {
+		return nil, err
+	}
+	info.fetchedAt = c.clock.Now()
+	c.repoInfoCache[repo] = &info
+	return &info, nil
+}
+
+// compactCount renders large counts like GitHub does, e.g. 1234 as "1.2k".
+func compactCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1_000_000), ".0") + "M"
+	case n >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1000), ".0") + "k"
+	}
+	return fmt.Sprint(n)
+}
+
+// repoContextLine renders the repo's primary language and stars, e.g.
+// "Go · ★ 1.2k".
+func (c *MyPlugin) repoContextLine(n GithubNotification) string {
+	info, err := c.fetchRepoInfo(n.Repository.FullName)
+	if err != nil {
+		c.recordError(fmt.Sprintf("fetching repository %s", n.Repository.FullName), err)
+		return ""
+	}
+	stars := "★ " + compactCount(info.StargazersCount)
+	if info.Language == "" {
+		return stars
+	}
+	return info.Language + " · " + stars
+}
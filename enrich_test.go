@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowEngagement(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	srv.handle("/repos/octocat/hello-world/issues/42", serveJSON(`{"comments":14,"reactions":{"total_count":8}}`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"showEngagement": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	notifications := `[{"id":"2","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Fix the thing",` +
+		`"type":"PullRequest","url":"` + srv.URL + `/repos/octocat/hello-world/pulls/42"}}]`
+	srv.handle("/notifications", serveJSON(notifications))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New PR notification in octocat/hello-world\n💬 14 · 👍 8", msgs[0].Message)
+}
+
+func TestShowDiffStat(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/pulls/42", serveJSON(`{"additions":120,"deletions":34,"changed_files":5}`))
+	srv.handle("/repos/octocat/hello-world/pulls/43", serveJSON(`{"additions":null,"deletions":null,"changed_files":null}`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"showDiffStat": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	notifications := `[` +
+		`{"id":"2","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Fix the thing",` +
+		`"type":"PullRequest","url":"` + srv.URL + `/repos/octocat/hello-world/pulls/42"}},` +
+		`{"id":"3","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Huge refactor",` +
+		`"type":"PullRequest","url":"` + srv.URL + `/repos/octocat/hello-world/pulls/43"}}]`
+	srv.handle("/notifications", serveJSON(notifications))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "New PR notification in octocat/hello-world\n+120 −34 · 5 files", msgs[0].Message)
+	assert.Equal(t, "New PR notification in octocat/hello-world", msgs[1].Message)
+}
+
+func TestShowRepoContext(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	requests := 0
+	srv.handle("/repos/octocat/hello-world", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		serveJSON(`{"language":"Go","stargazers_count":1234}`)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"showRepoContext": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	delete(p.seenNotifications, "2")
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "New PR notification in octocat/hello-world\nGo · ★ 1.2k", msgs[0].Message)
+	assert.Equal(t, 1, requests, "repo data is cached")
+}
+
+func TestCompactCount(t *testing.T) {
+	assert.Equal(t, "999", compactCount(999))
+	assert.Equal(t, "1k", compactCount(1000))
+	assert.Equal(t, "12.3k", compactCount(12345))
+	assert.Equal(t, "2M", compactCount(2_000_000))
+}
+
+func TestShowPullState(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	requests := 0
+	srv.handle("/repos/octocat/hello-world/pulls/41", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		serveJSON(`{"state":"closed","merged":true,"additions":1,"deletions":1,"changed_files":1}`)(w, r)
+	})
+	srv.handle("/repos/octocat/hello-world/pulls/42", serveJSON(`{"state":"closed","merged":false}`))
+	srv.handle("/repos/octocat/hello-world/pulls/43", serveJSON(`{"state":"open","merged":false}`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"showPullState": true, "showDiffStat": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	pull := func(id, number, title string) string {
+		return `{"id":"` + id + `","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"` + title + `",` +
+			`"type":"PullRequest","url":"` + srv.URL + `/repos/octocat/hello-world/pulls/` + number + `"},` +
+			`"updated_at":"2024-05-01T10:00:00Z"}`
+	}
+	srv.handle("/notifications", serveJSON(`[`+pull("1", "41", "Add feature")+`,`+
+		pull("2", "42", "Abandoned idea")+`,`+pull("3", "43", "Fix the thing")+`]`))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "🟣 Merged: [PR] Add feature", msgs[0].Title)
+	assert.Equal(t, 4, msgs[0].Priority)
+	assert.Equal(t, "🔴 Closed: [PR] Abandoned idea", msgs[1].Title)
+	assert.Equal(t, 3, msgs[1].Priority)
+	assert.Equal(t, "🟢 Opened: [PR] Fix the thing", msgs[2].Title)
+	assert.Equal(t, 2, msgs[2].Priority)
+	assert.Equal(t, 1, requests, "the pull request is fetched once for its state and diff stat")
+}
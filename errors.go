@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+type errorKind string
+
+const (
+	// errorKindNetwork covers DNS failures, refused or reset connections and
+	// timeouts. They are usually transient and simply retried on the next tick.
+	errorKindNetwork errorKind = "network"
+	// errorKindAuth means GitHub rejected the token.
+	errorKindAuth errorKind = "auth"
+	// errorKindRateLimit means the token's quota is exhausted until ResetAt.
+	errorKindRateLimit errorKind = "rate_limit"
+	// errorKindSSO means an organization requires the token to be authorized
+	// for SAML single sign-on. Other organizations keep working.
+	errorKindSSO errorKind = "sso"
+	// errorKindAPI covers every other unexpected response.
+	errorKindAPI errorKind = "api"
+)
+
+type fetchError struct {
+	Kind       errorKind
+	StatusCode int
+	ResetAt    time.Time
+	Endpoint   string
+	RequestID  string
+	Err        error
+}
+
+func (e *fetchError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.Err
+}
+
+// transient reports whether retrying later is likely to succeed: the
+// network failed or GitHub answered with a server error.
+func (e *fetchError) transient() bool {
+	return e.Kind == errorKindNetwork || e.Kind == errorKindAPI && e.StatusCode >= 500
+}
+
+func networkError(req *http.Request, err error) error {
+	return &fetchError{Kind: errorKindNetwork, Endpoint: req.URL.Path, Err: err}
+}
+
+// responseError classifies a non-successful GitHub response.
+func responseError(resp *http.Response) error {
+	fe := &fetchError{
+		Kind:       errorKindAPI,
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-GitHub-Request-Id"),
+		Err:        fmt.Errorf("unexpected status %s", resp.Status),
+	}
+	if resp.Request != nil {
+		fe.Endpoint = resp.Request.URL.Path
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		fe.Kind = errorKindAuth
+	case resp.StatusCode == http.StatusForbidden && ssoAuthorizationURL(resp.Header) != "":
+		fe.Kind = errorKindSSO
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		fe.Kind = errorKindRateLimit
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			fe.ResetAt = time.Unix(reset, 0)
+		}
+	}
+	return fe
+}
+
+// classifyError returns err as a *fetchError. Errors that did not come from
+// the fetch layer, such as undecodable bodies, are treated as API errors.
+func classifyError(err error) *fetchError {
+	var fe *fetchError
+	if errors.As(err, &fe) {
+		return fe
+	}
+	return &fetchError{Kind: errorKindAPI, Err: err}
+}
+
+type errorStatus struct {
+	Op         string    `json:"op"`
+	Kind       errorKind `json:"kind"`
+	Endpoint   string    `json:"endpoint,omitempty"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Message    string    `json:"message"`
+	Time       time.Time `json:"time"`
+}
+
+// recentErrorsSize is how many failures GET /errors can return.
+const recentErrorsSize = 50
+
+// PluginError describes a failed operation: what the plugin was doing, the
+// GitHub endpoint involved, the HTTP status and GitHub request ID when there
+// was a response, and the underlying error.
+type PluginError struct {
+	Op         string
+	Kind       errorKind
+	Endpoint   string
+	StatusCode int
+	RequestID  string
+	Time       time.Time
+	Err        error
+}
+
+func (e *PluginError) Error() string {
+	if e.Endpoint != "" {
+		return fmt.Sprintf("%s (%s): %v", e.Op, e.Endpoint, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PluginError) status() *errorStatus {
+	return &errorStatus{
+		Op:         e.Op,
+		Kind:       e.Kind,
+		Endpoint:   e.Endpoint,
+		StatusCode: e.StatusCode,
+		RequestID:  e.RequestID,
+		Message:    e.Err.Error(),
+		Time:       e.Time,
+	}
+}
+
+// recordError logs a failed operation and keeps it in the buffer of recent
+// failures served by GET /errors.
+func (c *MyPlugin) recordError(op string, err error) *PluginError {
+	fe := classifyError(err)
+	pe := &PluginError{
+		Op:         op,
+		Kind:       fe.Kind,
+		Endpoint:   fe.Endpoint,
+		StatusCode: fe.StatusCode,
+		RequestID:  fe.RequestID,
+		Time:       c.clock.Now(),
+		Err:        err,
+	}
+	c.getLogger().Error("operation failed", "op", op, "kind", pe.Kind, "endpoint", pe.Endpoint, "status", pe.StatusCode, "request_id", pe.RequestID, "err", err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recentErrors = append(c.recentErrors, pe)
+	if len(c.recentErrors) > recentErrorsSize {
+		c.recentErrors = c.recentErrors[len(c.recentErrors)-recentErrorsSize:]
+	}
+	return pe
+}
+
+// getRecentErrors returns the recorded failures, newest first.
+func (c *MyPlugin) getRecentErrors() []*errorStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]*errorStatus, 0, len(c.recentErrors))
+	for i := len(c.recentErrors) - 1; i >= 0; i-- {
+		list = append(list, c.recentErrors[i].status())
+	}
+	return list
+}
+
+func (c *MyPlugin) isPaused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pausedForAuth || !c.pausedAt.IsZero() || c.clock.Now().Before(c.pausedUntil)
+}
+
+// pollSucceeded clears the error state after a successful poll.
+func (c *MyPlugin) pollSucceeded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastSuccess = c.clock.Now()
+	c.lastError = nil
+	c.lastAlert = ""
+	c.pollFailures = 0
+}
+
+// handlePollError decides how the poller reacts to a failed fetch: network
+// and server errors are retried with exponential backoff, an invalid token
+// pauses polling until the configuration changes and a rate limit pauses it
+// until the quota resets. Alerts are sent once per distinct failure.
+func (c *MyPlugin) handlePollError(err error) {
+	if errors.Is(err, context.Canceled) {
+		// Disable aborted the request; there is nothing to report.
+		return
+	}
+	fe := classifyError(err)
+	pe := c.recordError("polling GitHub", err)
+
+	c.mu.Lock()
+	c.lastError = pe.status()
+	if fe.transient() {
+		c.pollFailures++
+	}
+	switch fe.Kind {
+	case errorKindAuth:
+		c.pausedForAuth = true
+		c.user = authUser{}
+		c.userFetchedAt = time.Time{}
+	case errorKindRateLimit:
+		c.pausedUntil = fe.ResetAt
+	}
+	c.mu.Unlock()
+	if fe.Kind == errorKindRateLimit && !fe.ResetAt.IsZero() {
+		c.infoLog("rate limit exhausted, pausing polling", "wait", fe.ResetAt.Sub(c.clock.Now()).Round(time.Second))
+	}
+
+	switch fe.Kind {
+	case errorKindNetwork:
+		if c.alertOnNetworkErrors {
+			c.alertOnce(fe, c.translate("alert.network"), 2)
+		}
+	case errorKindAuth:
+		if c.alertOnAPIErrors {
+			c.alertOnce(fe, c.translate("alert.auth"), 8)
+		}
+	case errorKindSSO:
+		// Already reported with the authorization link by checkSSO.
+	case errorKindRateLimit:
+		if c.alertOnAPIErrors {
+			c.alertOnce(fe, c.translate("alert.rateLimit", c.formatTime(fe.ResetAt)), 4)
+		}
+	default:
+		if c.detectOutages && fe.StatusCode >= 500 {
+			// Single server errors are retried quietly; sustained ones are
+			// reported once as an outage.
+			c.noteServerError()
+			break
+		}
+		if c.alertOnAPIErrors {
+			c.alertOnce(fe, c.translate("alert.api"), 4)
+		}
+	}
+}
+
+func (c *MyPlugin) alertOnce(fe *fetchError, title string, priority int) {
+	key := fmt.Sprintf("%s:%d", fe.Kind, fe.StatusCode)
+	c.mu.Lock()
+	if c.lastAlert == key {
+		c.mu.Unlock()
+		return
+	}
+	c.lastAlert = key
+	c.mu.Unlock()
+
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  fe.Err.Error(),
+		Priority: priority,
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending error alert", err)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseErrorClassification(t *testing.T) {
+	resp := func(code int, headers map[string]string) *http.Response {
+		r := &http.Response{StatusCode: code, Status: http.StatusText(code), Header: http.Header{}}
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		return r
+	}
+
+	assert.Equal(t, errorKindAuth, classifyError(responseError(resp(401, nil))).Kind)
+	assert.Equal(t, errorKindAPI, classifyError(responseError(resp(403, nil))).Kind)
+	assert.Equal(t, errorKindAPI, classifyError(responseError(resp(502, nil))).Kind)
+	assert.Equal(t, errorKindNetwork, classifyError(networkError(httptest.NewRequest(http.MethodGet, "/notifications", nil), errors.New("connection reset"))).Kind)
+	assert.Equal(t, errorKindAPI, classifyError(errors.New("invalid character")).Kind)
+
+	fe := classifyError(responseError(resp(403, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000000"})))
+	assert.Equal(t, errorKindRateLimit, fe.Kind)
+	assert.Equal(t, time.Unix(1700000000, 0), fe.ResetAt)
+}
+
+func TestAuthErrorPausesPollingAndAlertsOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"notifyOnError": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	p.poll()
+	p.checkNotifications()
+	assert.True(t, p.isPaused())
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 8, msgs[0].Priority)
+
+	w := httptest.NewRecorder()
+	newWebhookRouter(p).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	var status statusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Paused)
+	require.NotNil(t, status.LastError)
+	assert.Equal(t, errorKindAuth, status.LastError.Kind)
+}
+
+func TestNewConfigurationResumesAfterAuthError(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	p.handlePollError(responseError(&http.Response{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}))
+	require.True(t, p.isPaused())
+
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "new-token"}))
+	assert.False(t, p.isPaused())
+}
+
+func TestNetworkErrorsDoNotAlertByDefault(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.handlePollError(networkError(httptest.NewRequest(http.MethodGet, "/notifications", nil), fmt.Errorf("dial tcp: lookup api.github.com: no such host")))
+	assert.Empty(t, rec.Messages())
+	assert.False(t, p.isPaused())
+}
+
+func TestErrorsEndpoint(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		http.Error(w, "boom", http.StatusBadGateway)
+	})
+	p, _ := newTestPlugin(t, srv, nil)
+	p.seenNotifications = seenSet{}
+	p.checkNotifications()
+	p.recordError("sending github notification", errors.New("gotify unavailable"))
+
+	w := httptest.NewRecorder()
+	newWebhookRouter(p).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/errors", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []errorStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "sending github notification", list[0].Op, "newest first")
+	assert.Equal(t, "polling GitHub", list[1].Op)
+	assert.Equal(t, "/notifications", list[1].Endpoint)
+	assert.Equal(t, http.StatusBadGateway, list[1].StatusCode)
+	assert.Equal(t, "ABCD:1234", list[1].RequestID)
+	assert.Equal(t, errorKindAPI, list[1].Kind)
+
+	for i := 0; i < recentErrorsSize+10; i++ {
+		p.recordError("test", errors.New("x"))
+	}
+	assert.Len(t, p.getRecentErrors(), recentErrorsSize)
+}
+
+func TestSustainedErrorAlertsOnlyOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "notifyOnError": true, "alertOnNetworkErrors": true})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+
+	var mu sync.Mutex
+	requests := 0
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	})
+	for i := 1; i <= 3; i++ {
+		// Far enough to get past the backoff after each failure.
+		clk.Advance(time.Hour)
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return requests == i
+		}, time.Second, time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "a recurring error is reported once")
+	assert.Equal(t, "GitHub API error", msgs[0].Title)
+	assert.Contains(t, msgs[0].Message, "502")
+}
+
+func TestRevokedTokenHaltsPolling(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]int{}
+	revoked := false
+	srv := newFixtureServer(t)
+	handler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests[r.URL.Path]++
+			isRevoked := revoked
+			mu.Unlock()
+			if isRevoked {
+				http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+				return
+			}
+			serveJSON(body)(w, r)
+		}
+	}
+	srv.handle("/notifications", handler(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"},{"full_name":"octocat/spoon-knife"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", handler(`[]`))
+	srv.handle("/repos/octocat/spoon-knife/stargazers", handler(`[]`))
+	// One worker, so the second repo is not already in flight.
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "starWorkers": 1, "notifyOnError": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	mu.Lock()
+	revoked = true
+	requests = map[string]int{}
+	mu.Unlock()
+	p.checkStars()
+	assert.True(t, p.isPaused(), "a 401 on any watcher pauses polling")
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.Contains(t, msgs[0].Title, "token rejected")
+
+	p.poll()
+	p.poll()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"/repos/octocat/hello-world/stargazers": 1}, requests,
+		"nothing is fetched again until the token is updated")
+	assert.Len(t, rec.Messages(), 1)
+}
+
+func TestErrorAlertsAreOptIn(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+	})
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "failures are only sent with notifyOnError")
+	assert.NotEmpty(t, p.getRecentErrors(), "but still recorded")
+}
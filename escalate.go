@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+type unreadThread struct {
+	firstSeen time.Time
+	level     int
+	// priority is the one the thread was sent with, where escalation starts.
+	priority int
+	// ignored threads were filtered out, muted or suppressed, so they were
+	// never sent and are not escalated either.
+	ignored bool
+}
+
+// parseEscalateAfter parses a comma-separated list of minutes, e.g. "60,240".
+func parseEscalateAfter(s string) ([]time.Duration, error) {
+	var schedule []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		minutes, err := strconv.Atoi(part)
+		if err != nil || minutes <= 0 {
+			return nil, fmt.Errorf("escalateAfter: %q is not a positive number of minutes", part)
+		}
+		d := time.Duration(minutes) * time.Minute
+		if len(schedule) > 0 && d <= schedule[len(schedule)-1] {
+			return nil, fmt.Errorf("escalateAfter must be in ascending order")
+		}
+		schedule = append(schedule, d)
+	}
+	return schedule, nil
+}
+
+func formatAge(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// trackUnreadThread starts the escalation clock of a thread once it is sent,
+// or marks it ignored when it is not. It does nothing unless escalateUnread
+// is set.
+func (c *MyPlugin) trackUnreadThread(id string, priority int, ignored bool) {
+	if !c.escalateUnread {
+		return
+	}
+	if _, ok := c.unreadThreads[id]; !ok {
+		c.unreadThreads[id] = &unreadThread{firstSeen: c.clock.Now(), priority: priority, ignored: ignored}
+	}
+}
+
+// escalateUnreadThreads re-notifies threads that are still in the unread
+// list after each step of the escalation schedule, bumping the priority each
+// time. Threads that dropped out of the list were read and stop escalating.
+// Threads already listed when the plugin started are classified here, the
+// others when checkNotifications first saw them. Releases held until their
+// assets are uploaded are tracked once they are sent.
+func (c *MyPlugin) escalateUnreadThreads(unread []GithubNotification, filter *notificationFilter) {
+	now := c.clock.Now()
+	current := make(map[string]bool, len(unread))
+	for _, notification := range unread {
+		current[notification.ID] = true
+		thread, ok := c.unreadThreads[notification.ID]
+		if !ok {
+			if _, held := c.pendingReleases[notification.ID]; held {
+				continue
+			}
+			priority, _, skip := c.classifyNotification(notification, filter)
+			c.trackUnreadThread(notification.ID, priority, skip != "")
+			continue
+		}
+		if thread.ignored || thread.level >= len(c.escalateSchedule) || c.isSnoozed(notification.ID) {
+			continue
+		}
+		step := c.escalateSchedule[thread.level]
+		if now.Sub(thread.firstSeen) < step {
+			continue
+		}
+		thread.level++
+
+		priority := min(thread.priority+c.escalatePriorityStep*thread.level, 10)
+		label, _ := notificationLabel(notification.Subject.Type)
+		msg := &plugin.Message{
+			Title:    c.translate("escalate.title", formatAge(step), notification.Subject.Title),
+			Message:  c.translate("escalate.body", c.typeName(label), notification.Repository.FullName),
+			Priority: priority,
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": c.subjectWebURL(notification),
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(*msg); err != nil {
+			c.recordError(fmt.Sprintf("sending escalation for %s", notification.ID), err)
+		}
+	}
+
+	for id := range c.unreadThreads {
+		if !current[id] {
+			delete(c.unreadThreads, id)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEscalateAfter(t *testing.T) {
+	schedule, err := parseEscalateAfter("60, 240")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Hour, 4 * time.Hour}, schedule)
+
+	_, err = parseEscalateAfter("60,30")
+	assert.Error(t, err)
+	_, err = parseEscalateAfter("soon")
+	assert.Error(t, err)
+}
+
+func TestEscalateUnreadThreads(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"escalateUnread": true, "escalateAfter": "60,240"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.checkNotifications()
+	require.Contains(t, p.unreadThreads, "1")
+	assert.Empty(t, rec.Messages())
+
+	p.unreadThreads["1"].firstSeen = time.Now().Add(-61 * time.Minute)
+	p.checkNotifications()
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Still unread after 1h: Add README", msgs[0].Title)
+	assert.Equal(t, 4, msgs[0].Priority)
+
+	srv.handle("/notifications", serveJSON(`[]`))
+	p.checkNotifications()
+	assert.NotContains(t, p.unreadThreads, "1", "read threads stop escalating")
+}
+
+func TestEscalationSkipsFilteredAndMutedThreads(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"escalateUnread": true, "escalateAfter": "60",
+		"repoPriorities": map[string]interface{}{"octocat/noisy": -1},
+		"excludeRepos":   "octocat/hidden",
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+repoNotificationJSON("1", "octocat/noisy")+`,`+
+		repoNotificationJSON("2", "octocat/hidden")+`,`+repoNotificationJSON("3", "octocat/other")+`]`))
+	p.checkNotifications()
+	require.Len(t, rec.Messages(), 1)
+
+	for _, thread := range p.unreadThreads {
+		thread.firstSeen = thread.firstSeen.Add(-61 * time.Minute)
+	}
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2, "only the thread that was sent escalates")
+	assert.Equal(t, "Still unread after 1h: T3", msgs[1].Title)
+}
+
+func TestEscalationSkipsThreadsHeldBack(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/releases/7", serveJSON(`{"assets":[{"name":"app.tar.gz","state":"starter"}]}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"escalateUnread": true, "escalateAfter": "60",
+		"repoMinGap": 10, "waitForReleaseAssets": true,
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.repoLastSent["octocat/busy"] = time.Now()
+	release := `{"id":"5","repository":{"full_name":"octocat/hello-world"},` +
+		`"subject":{"title":"v1.0.0","type":"Release","url":"` + srv.URL + `/repos/octocat/hello-world/releases/7"}}`
+	srv.handle("/notifications", serveJSON(`[`+repoNotificationJSON("1", "octocat/busy")+`,`+release+`]`))
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages())
+	require.Contains(t, p.unreadThreads, "1")
+	assert.True(t, p.unreadThreads["1"].ignored, "a thread held back by the repo's minimum gap was never sent")
+	assert.NotContains(t, p.unreadThreads, "5", "a held release is tracked once it is sent")
+
+	for _, thread := range p.unreadThreads {
+		thread.firstSeen = thread.firstSeen.Add(-61 * time.Minute)
+	}
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "held threads do not escalate")
+	assert.NotContains(t, p.unreadThreads, "5")
+
+	srv.handle("/repos/octocat/hello-world/releases/7", serveJSON(`{"assets":[{"name":"app.tar.gz","state":"uploaded"}]}`))
+	p.checkNotifications()
+	require.Len(t, rec.Messages(), 1)
+	require.Contains(t, p.unreadThreads, "5")
+	assert.False(t, p.unreadThreads["5"].ignored)
+}
+
+func TestEscalationStartsFromThreadPriority(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"escalateUnread": true, "escalateAfter": "60,120",
+		"repoPriorities": map[string]interface{}{"octocat/critical": 7},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+repoNotificationJSON("1", "octocat/critical")+`]`))
+	p.checkNotifications()
+	require.Equal(t, 7, rec.Messages()[0].Priority)
+
+	p.unreadThreads["1"].firstSeen = time.Now().Add(-61 * time.Minute)
+	p.checkNotifications()
+	p.unreadThreads["1"].firstSeen = time.Now().Add(-121 * time.Minute)
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, 9, msgs[1].Priority)
+	assert.Equal(t, 10, msgs[2].Priority, "escalation stops at the highest priority")
+}
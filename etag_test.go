@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveWithETag serves fixture with etag and answers 304 once the client
+// sends it back in If-None-Match.
+func serveWithETag(t *testing.T, fixture, etag string, conditional *int, mu *sync.Mutex) http.HandlerFunc {
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			mu.Lock()
+			*conditional++
+			mu.Unlock()
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+func TestNotModifiedSendsNothing(t *testing.T) {
+	var mu sync.Mutex
+	var notificationHits, starHits int
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveWithETag(t, "notifications_initial.json", `"n1"`, &notificationHits, &mu))
+	srv.serveFixture("/user/repos", "user_repos.json")
+	srv.handle("/repos/octocat/hello-world/stargazers", serveWithETag(t, "stargazers_initial.json", `"s1"`, &starHits, &mu))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.checkNotifications()
+	p.checkStars()
+
+	assert.Empty(t, rec.Messages())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, notificationHits, "notifications must be fetched with If-None-Match")
+	assert.Equal(t, 1, starHits, "stargazers must be fetched with If-None-Match")
+}
+
+func TestChangedETagDeliversNewNotifications(t *testing.T) {
+	var mu sync.Mutex
+	var hits int
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveWithETag(t, "notifications_initial.json", `"n1"`, &hits, &mu))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveWithETag(t, "notifications.json", `"n2"`, &hits, &mu))
+	p.checkNotifications()
+	require.Len(t, rec.Messages(), 1)
+	assert.Equal(t, `"n2"`, p.notificationsETag)
+
+	p.checkNotifications()
+	assert.Len(t, rec.Messages(), 1)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, hits)
+}
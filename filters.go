@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// knownReasons are the notification reasons GitHub documents.
+var knownReasons = []string{
+	"approval_requested", "assign", "author", "ci_activity", "comment",
+	"invitation", "manual", "member_feature_requested", "mention",
+	"review_requested", "security_advisory_credit", "security_alert",
+	"state_change", "subscribed", "team_mention",
+}
+
+// notificationFilter decides which notifications are delivered. Empty lists
+// allow everything; excludeRepos wins over includeRepos. Repo entries may
+// use the wildcards of path.Match.
+type notificationFilter struct {
+	IncludeRepos []string `json:"includeRepos"`
+	ExcludeRepos []string `json:"excludeRepos"`
+	Reasons      []string `json:"reasons"`
+	Types        []string `json:"types"`
+}
+
+func (f *notificationFilter) validate() error {
+	for _, pattern := range append(slices.Clone(f.IncludeRepos), f.ExcludeRepos...) {
+		if err := validateRepoPattern(pattern); err != nil {
+			return err
+		}
+	}
+	for _, reason := range f.Reasons {
+		if !slices.Contains(knownReasons, reason) {
+			return fmt.Errorf("unknown reason %q", reason)
+		}
+	}
+	for _, t := range f.Types {
+		if t == "" {
+			return fmt.Errorf("types must not contain empty entries")
+		}
+	}
+	return nil
+}
+
+// matchRepo matches repo against a path.Match pattern. GitHub treats owner
+// and repo names case-insensitively, so the match does too.
+func matchRepo(pattern, repo string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(repo))
+	return ok
+}
+
+func matchesAnyRepo(patterns []string, repo string) bool {
+	for _, pattern := range patterns {
+		if matchRepo(pattern, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *notificationFilter) allows(n GithubNotification) bool {
+	repo := n.Repository.FullName
+	if matchesAnyRepo(f.ExcludeRepos, repo) {
+		return false
+	}
+	if len(f.IncludeRepos) > 0 && !matchesAnyRepo(f.IncludeRepos, repo) {
+		return false
+	}
+	if len(f.Reasons) > 0 && !slices.Contains(f.Reasons, n.Reason) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, n.Subject.Type) {
+		return false
+	}
+	return true
+}
+
+// activeFilter returns the filter set through POST /filters, or the
+// configured one. Filters are never modified once set, so the result can be
+// used without holding the lock.
+func (c *MyPlugin) activeFilter() *notificationFilter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.filterOverride != nil {
+		return c.filterOverride
+	}
+	if c.configFilter != nil {
+		return c.configFilter
+	}
+	return &notificationFilter{}
+}
+
+// filterUpdate is the body of POST /filters. Omitted lists keep their
+// current value; an empty list clears it.
+type filterUpdate struct {
+	IncludeRepos *[]string `json:"includeRepos"`
+	ExcludeRepos *[]string `json:"excludeRepos"`
+	Reasons      *[]string `json:"reasons"`
+	Types        *[]string `json:"types"`
+}
+
+func (c *MyPlugin) setFilterOverride(f *notificationFilter) error {
+	c.mu.Lock()
+	c.filterOverride = f
+	c.mu.Unlock()
+	if c.store == nil {
+		return nil
+	}
+	return c.store.update(c.storageKey, func(state *accountState) { state.Filter = f })
+}
+
+func (c *MyPlugin) handleGetFilters(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.activeFilter())
+}
+
+// handleSetFilters validates the whole update before swapping it in, so a
+// bad entry leaves the current filters untouched.
+func (c *MyPlugin) handleSetFilters(ctx *gin.Context) {
+	var update filterUpdate
+	if err := ctx.ShouldBindJSON(&update); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	next := *c.activeFilter()
+	for _, field := range []struct {
+		src *[]string
+		dst *[]string
+	}{
+		{update.IncludeRepos, &next.IncludeRepos},
+		{update.ExcludeRepos, &next.ExcludeRepos},
+		{update.Reasons, &next.Reasons},
+		{update.Types, &next.Types},
+	} {
+		if field.src != nil {
+			*field.dst = slices.Clone(*field.src)
+		}
+	}
+	if err := next.validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.setFilterOverride(&next); err != nil {
+		c.recordError("saving filters", err)
+	}
+	ctx.JSON(http.StatusOK, &next)
+}
+
+// handleResetFilters drops the runtime filters and returns to the
+// configured ones.
+func (c *MyPlugin) handleResetFilters(ctx *gin.Context) {
+	if err := c.setFilterOverride(nil); err != nil {
+		c.recordError("saving filters", err)
+	}
+	ctx.JSON(http.StatusOK, c.activeFilter())
+}
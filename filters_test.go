@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationFilter(t *testing.T) {
+	n := GithubNotification{Reason: "mention"}
+	n.Repository.FullName = "octocat/hello-world"
+	n.Subject.Type = "Issue"
+
+	assert.True(t, (&notificationFilter{}).allows(n))
+	assert.True(t, (&notificationFilter{IncludeRepos: []string{"octocat/*"}}).allows(n))
+	assert.False(t, (&notificationFilter{IncludeRepos: []string{"other/*"}}).allows(n))
+	assert.False(t, (&notificationFilter{IncludeRepos: []string{"octocat/*"}, ExcludeRepos: []string{"octocat/hello-world"}}).allows(n))
+	assert.True(t, (&notificationFilter{Reasons: []string{"mention", "assign"}}).allows(n))
+	assert.False(t, (&notificationFilter{Reasons: []string{"assign"}}).allows(n))
+	assert.False(t, (&notificationFilter{Types: []string{"PullRequest"}}).allows(n))
+}
+
+func TestFiltersConfig(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"excludeRepos": "octocat/*"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages())
+}
+
+func TestFiltersValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "reasons": "because"}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "includeRepos": "octocat"}))
+}
+
+func TestFiltersWebhook(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"allowManagement": true, "webhookSecret": "s3cret", "types": "Issue",
+	})
+	storage := &memoryStorage{}
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	r := newWebhookRouter(p)
+	send := func(method, body string) (int, notificationFilter) {
+		req := httptest.NewRequest(method, "/filters", bytes.NewBufferString(body))
+		req.Header.Set("X-Webhook-Secret", "s3cret")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var f notificationFilter
+		json.Unmarshal(w.Body.Bytes(), &f)
+		return w.Code, f
+	}
+
+	code, _ := send(http.MethodPost, `{"reasons": ["because"]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []string{"Issue"}, p.activeFilter().Types, "invalid updates change nothing")
+
+	code, f := send(http.MethodPost, `{"types": ["Issue", "PullRequest"], "reasons": ["review_requested"]}`)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, notificationFilter{Types: []string{"Issue", "PullRequest"}, Reasons: []string{"review_requested"}}, f)
+	assert.Equal(t, &f, p.loadState().Filter, "the filters are persisted")
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	require.Len(t, rec.Messages(), 1)
+
+	code, f = send(http.MethodDelete, ``)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"Issue"}, f.Types)
+	assert.Nil(t, p.loadState().Filter)
+
+	req := httptest.NewRequest(http.MethodPost, "/filters", bytes.NewBufferString(`{}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRepoFilters(t *testing.T) {
+	notifications := "[" + notificationJSON("1", "Core") + "," +
+		`{"id":"2","repository":{"full_name":"MyOrg/tools"},"subject":{"title":"Tools","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"},` +
+		`{"id":"3","repository":{"full_name":"myorg/docs"},"subject":{"title":"Docs","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}]`
+	for _, tc := range []struct {
+		name string
+		conf map[string]interface{}
+		want []string
+	}{
+		{"include only", map[string]interface{}{"includeRepos": "octocat/hello-world"}, []string{"[Issue] Core"}},
+		{"exclude only", map[string]interface{}{"excludeRepos": "octocat/hello-world"}, []string{"[Issue] Tools", "[Issue] Docs"}},
+		{"wildcard", map[string]interface{}{"includeRepos": "myorg/*"}, []string{"[Issue] Tools", "[Issue] Docs"}},
+		{"wildcard with exclude", map[string]interface{}{"includeRepos": "myorg/*", "excludeRepos": "*/docs"}, []string{"[Issue] Tools"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newFixtureServer(t)
+			srv.handle("/notifications", serveJSON(`[]`))
+			p, rec := newTestPlugin(t, srv, tc.conf)
+			require.NoError(t, p.Enable())
+			defer p.Disable()
+
+			srv.handle("/notifications", serveJSON(notifications))
+			p.checkNotifications()
+			var titles []string
+			for _, msg := range rec.Messages() {
+				titles = append(titles, msg.Title)
+			}
+			assert.Equal(t, tc.want, titles)
+		})
+	}
+}
+
+func reasonNotificationJSON(id, reason string) string {
+	return fmt.Sprintf(`{"id":%q,"reason":%q,"repository":{"full_name":"octocat/hello-world"},`+
+		`"subject":{"title":"T%s","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}`, id, reason, id)
+}
+
+func TestReasonFilter(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"reasons": "mention,review_requested"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON("["+reasonNotificationJSON("1", "mention")+","+reasonNotificationJSON("2", "subscribed")+"]"))
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[Issue] T1", msgs[0].Title)
+}
+
+func TestReasonFilterAppliesToReplay(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") == "true" {
+			serveJSON("["+reasonNotificationJSON("1", "mention")+","+reasonNotificationJSON("2", "subscribed")+"]")(w, r)
+			return
+		}
+		serveJSON(`[]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"reasons": "mention", "replayOnEnable": true})
+	clk := newFakeClock()
+	p.clock = clk
+	p.replayThrottle = 0
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.store.put("", accountState{LastCheckTime: clk.Now().Add(-time.Hour)}))
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	require.Eventually(t, func() bool { return len(rec.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[Issue] T1", msgs[0].Title)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gotify/plugin-api"
+)
+
+type follower struct {
+	Login string `json:"login"`
+}
+
+// scanFollowers reports accounts that started following the token's user
+// since the last scan. The first scan after Enable only records the current
+// followers.
+func (c *MyPlugin) scanFollowers(notify bool) {
+	followers, err := fetchAllPages[follower](c, c.baseURL+"/user/followers?per_page=100", "application/vnd.github.v3+json")
+	if err != nil {
+		c.recordError("fetching followers", err)
+		return
+	}
+
+	for _, f := range followers {
+		if c.seenFollowers[f.Login] {
+			continue
+		}
+		c.seenFollowers[f.Login] = true
+		if !notify {
+			continue
+		}
+
+		msg := &plugin.Message{
+			Title:    "New Follower",
+			Message:  fmt.Sprintf("%s is now following you", f.Login),
+			Priority: 2,
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": c.webURL(f.Login),
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(*msg); err != nil {
+			c.recordError("sending follower notification", err)
+		} else {
+			c.infoLog("sent follower notification", "login", f.Login)
+		}
+	}
+}
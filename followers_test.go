@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanFollowersReportsNewFollowerOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	secondPage := `[{"login":"bob"}]`
+	srv.handle("/user/followers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON(secondPage)(w, r)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/user/followers?page=2>; rel="next"`, srv.URL))
+		serveJSON(`[{"login":"alice"}]`)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchFollowers": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, rec.Messages(), "existing followers are not announced")
+
+	secondPage = `[{"login":"bob"},{"login":"carol"}]`
+	p.scanFollowers(true)
+	p.scanFollowers(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New Follower", msgs[0].Title)
+	assert.Equal(t, "carol is now following you", msgs[0].Message)
+}
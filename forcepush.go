@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gotify/plugin-api"
+)
+
+type pushEvent struct {
+	Type  string `json:"type"`
+	Actor struct {
+		Login string `json:"login"`
+	} `json:"actor"`
+	Payload struct {
+		Head string `json:"head"`
+	} `json:"payload"`
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// scanForcePushes tracks the head of the default branch of every watched
+// repo. When the new head does not descend from the last seen one, the
+// branch history was rewritten and a high priority alert names the old and
+// new SHA and, when the events show it, the pusher. The heads are persisted
+// so rewrites during downtime are caught too. Repos the token cannot read
+// are skipped until the plugin is re-enabled.
+func (c *MyPlugin) scanForcePushes() {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for force-push watch", err)
+		return
+	}
+
+	changed := false
+	for _, repo := range repos {
+		if repo.DefaultBranch == "" || c.branchHeadsForbidden[repo.FullName] {
+			continue
+		}
+		var branch struct {
+			Commit struct {
+				SHA string `json:"sha"`
+			} `json:"commit"`
+		}
+		endpoint := fmt.Sprintf("%s/repos/%s/branches/%s", c.baseURL, repo.FullName, url.PathEscape(repo.DefaultBranch))
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &branch); err != nil {
+			if status := classifyError(err).StatusCode; status == http.StatusForbidden || status == http.StatusNotFound {
+				c.infoLog("skipping force-push watch, the branch is not readable", "repo", repo.FullName)
+				c.branchHeadsForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching branch %s of %s", repo.DefaultBranch, repo.FullName), err)
+			}
+			continue
+		}
+
+		head := branch.Commit.SHA
+		previous := c.branchHeads[repo.FullName]
+		if head == previous {
+			continue
+		}
+		c.branchHeads[repo.FullName] = head
+		changed = true
+		if previous == "" || c.isFastForward(repo.FullName, previous, head) {
+			continue
+		}
+
+		pusher := c.forcePusher(repo, head)
+		message := fmt.Sprintf("%s was rewritten: %s → %s", repo.DefaultBranch, shortSHA(previous), shortSHA(head))
+		if pusher != "" {
+			message = fmt.Sprintf("%s force-pushed %s: %s → %s", pusher, repo.DefaultBranch, shortSHA(previous), shortSHA(head))
+		}
+		msg := &plugin.Message{
+			Title:    fmt.Sprintf("Force-push to %s in %s", repo.DefaultBranch, repo.FullName),
+			Message:  message,
+			Priority: 8,
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": c.webURL(fmt.Sprintf("%s/compare/%s...%s", repo.FullName, previous, head)),
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(*msg); err != nil {
+			c.recordError("sending force-push alert", err)
+		} else {
+			c.infoLog("sent force-push alert", "repo", repo.FullName)
+		}
+	}
+	if changed {
+		c.saveState()
+	}
+}
+
+// isFastForward reports whether head descends from previous. A previous
+// commit GitHub no longer knows was dropped by a rewrite.
+func (c *MyPlugin) isFastForward(repo, previous, head string) bool {
+	var comparison struct {
+		Status string `json:"status"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/compare/%s...%s", c.baseURL, repo, previous, head)
+	if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &comparison); err != nil {
+		if classifyError(err).StatusCode == http.StatusNotFound {
+			return false
+		}
+		c.recordError(fmt.Sprintf("comparing %s of %s", shortSHA(previous), repo), err)
+		return true
+	}
+	return comparison.Status == "ahead" || comparison.Status == "identical"
+}
+
+// forcePusher finds who pushed head in the recent events of repo.
+func (c *MyPlugin) forcePusher(repo Repo, head string) string {
+	var events []pushEvent
+	endpoint := fmt.Sprintf("%s/repos/%s/events?per_page=100", c.baseURL, repo.FullName)
+	if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &events); err != nil {
+		c.recordError(fmt.Sprintf("fetching events for %s", repo.FullName), err)
+		return ""
+	}
+	for _, event := range events {
+		if event.Type == "PushEvent" && event.Payload.Head == head {
+			return event.Actor.Login
+		}
+	}
+	return ""
+}
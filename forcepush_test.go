@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForcePushDetection(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world","default_branch":"main"},{"full_name":"octocat/secret","default_branch":"main"}]`))
+	srv.handle("/repos/octocat/hello-world/branches/main", serveJSON(`{"commit":{"sha":"bbbbbbbbbbbb"}}`))
+	srv.handle("/repos/octocat/hello-world/compare/aaaaaaaaaaaa...bbbbbbbbbbbb", serveJSON(`{"status":"ahead"}`))
+	srv.handle("/repos/octocat/hello-world/compare/bbbbbbbbbbbb...cccccccccccc", serveJSON(`{"status":"diverged"}`))
+	srv.handle("/repos/octocat/hello-world/events", serveJSON(`[{"type":"PushEvent","actor":{"login":"mallory"},"payload":{"head":"cccccccccccc"}}]`))
+	secret := 0
+	srv.handle("/repos/octocat/secret/branches/main", func(w http.ResponseWriter, r *http.Request) {
+		secret++
+		http.NotFound(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchForcePushes": true})
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.store.put("", accountState{BranchHeads: map[string]string{"octocat/hello-world": "aaaaaaaaaaaa"}}))
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, rec.Messages(), "a fast-forward is not a force-push")
+	assert.Equal(t, "bbbbbbbbbbbb", p.loadState().BranchHeads["octocat/hello-world"])
+
+	srv.handle("/repos/octocat/hello-world/branches/main", serveJSON(`{"commit":{"sha":"cccccccccccc"}}`))
+	p.scanForcePushes()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Force-push to main in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "mallory force-pushed main: bbbbbbb → ccccccc", msgs[0].Message)
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.Equal(t, 1, secret, "unreadable repos are skipped")
+
+	p.scanForcePushes()
+	assert.Len(t, rec.Messages(), 1)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gotify/plugin-api"
+)
+
+type fork struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// scanForks looks for new forks among the newest forks of every watched
+// repo. Repos whose forks the token cannot list are skipped until the plugin
+// is re-enabled.
+func (c *MyPlugin) scanForks(notify bool) {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for fork watch", err)
+		return
+	}
+
+	for _, repo := range repos {
+		if c.forksForbidden[repo.FullName] {
+			continue
+		}
+		var forks []fork
+		endpoint := fmt.Sprintf("%s/repos/%s/forks?sort=newest&per_page=30", c.baseURL, repo.FullName)
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &forks); err != nil {
+			if status := classifyError(err).StatusCode; status == http.StatusForbidden || status == http.StatusNotFound {
+				c.infoLog("skipping fork watch, the forks are not readable", "repo", repo.FullName)
+				c.forksForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching forks for %s", repo.FullName), err)
+			}
+			continue
+		}
+
+		// Forks are newest first; notify oldest first.
+		for i := len(forks) - 1; i >= 0; i-- {
+			f := forks[i]
+			key := repo.FullName + ":" + f.FullName
+			if c.seenForks[key] {
+				continue
+			}
+			c.seenForks[key] = true
+			if !notify {
+				continue
+			}
+
+			msg := &plugin.Message{
+				Title:    fmt.Sprintf("New fork of %s", repo.FullName),
+				Message:  fmt.Sprintf("%s forked it to %s", f.Owner.Login, f.FullName),
+				Priority: 2,
+				Extras: map[string]interface{}{
+					"client::notification": map[string]interface{}{
+						"click": map[string]interface{}{
+							"url": f.HTMLURL,
+						},
+					},
+				},
+			}
+			if err := c.msgHandler.SendMessage(*msg); err != nil {
+				c.recordError("sending fork notification", err)
+			} else {
+				c.infoLog("sent fork notification", "fork", f.FullName)
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanForksReportsNewForkOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	forks := `[{"full_name":"alice/hello-world","html_url":"https://github.com/alice/hello-world","owner":{"login":"alice"}}]`
+	srv.handle("/repos/octocat/hello-world/forks", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "newest", r.URL.Query().Get("sort"))
+		serveJSON(forks)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchForks": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, rec.Messages(), "existing forks are not announced")
+
+	forks = `[{"full_name":"bob/hello-world","html_url":"https://github.com/bob/hello-world","owner":{"login":"bob"}},` +
+		`{"full_name":"alice/hello-world","html_url":"https://github.com/alice/hello-world","owner":{"login":"alice"}}]`
+	p.scanForks(true)
+	p.scanForks(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New fork of octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "bob forked it to bob/hello-world", msgs[0].Message)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formatDefault = "default"
+	// formatCompact renders a single line like "owner/repo #42 PR: Fix the thing".
+	formatCompact = "compact"
+)
+
+// Title sources decide what ends up in the message title, which is the part
+// shown on a locked phone screen.
+const (
+	titleSubject = "subject"
+	titleRepo    = "repo"
+	// titleCustom expands titleTemplate with {repo}, {type}, {title} and {number}.
+	titleCustom = "custom"
+)
+
+// notificationLabel returns the short label for a GitHub subject type and
+// whether the type is one the plugin knows about.
+func notificationLabel(subjectType string) (string, bool) {
+	switch subjectType {
+	case "Issue", "Release", "Discussion":
+		return subjectType, true
+	case "PullRequest":
+		return "PR", true
+	default:
+		return subjectType, false
+	}
+}
+
+// defaultTypePriorities are the priorities of the subject types the plugin
+// knows and of star messages.
+func defaultTypePriorities() map[string]int {
+	return map[string]int{
+		"Issue":       2,
+		"PullRequest": 2,
+		"Release":     2,
+		"Discussion":  2,
+		"star":        2,
+
+		// Pull requests by state, with showPullState.
+		"PullRequest.merged": 4,
+		"PullRequest.closed": 3,
+	}
+}
+
+// typeEmoji marks the subject types the plugin knows, and stars, when
+// useEmoji is set.
+var typeEmoji = map[string]string{
+	"Issue":       "🐛",
+	"PullRequest": "🔀",
+	"Release":     "🏷️",
+	"Discussion":  "💬",
+	"star":        "⭐",
+}
+
+// typePrefix returns what precedes the title of a subject: "[PR]", or "🔀"
+// with useEmoji set. Types without an emoji keep the bracketed name.
+func (c *MyPlugin) typePrefix(subjectType, name string) string {
+	if emoji, ok := typeEmoji[subjectType]; ok && c.useEmoji {
+		return emoji
+	}
+	return "[" + name + "]"
+}
+
+// withTypeEmoji prepends the emoji of subjectType to title when useEmoji is
+// set.
+func (c *MyPlugin) withTypeEmoji(subjectType, title string) string {
+	if emoji, ok := typeEmoji[subjectType]; ok && c.useEmoji {
+		return emoji + " " + title
+	}
+	return title
+}
+
+// typePriority returns the configured priority of a subject type or of
+// "star". Unknown types without an entry use unknownTypePriority.
+func (c *MyPlugin) typePriority(subjectType string) int {
+	if priority, ok := c.typePriorities[subjectType]; ok {
+		return priority
+	}
+	if _, known := notificationLabel(subjectType); known || subjectType == "star" {
+		return 2
+	}
+	return c.unknownTypePriority
+}
+
+// defaultReasonMarkers maps notification reasons to the marker prepended to
+// the message body. Users override single reasons through reasonMarkers and
+// disable one by setting it to "".
+func defaultReasonMarkers() map[string]string {
+	return map[string]string{
+		"mention":          "@",
+		"team_mention":     "@",
+		"review_requested": "👀",
+		"assign":           "📌",
+		"author":           "✍️",
+		"comment":          "💬",
+		"state_change":     "🔄",
+		"ci_activity":      "⚙️",
+		"security_alert":   "🚨",
+		"invitation":       "✉️",
+		"manual":           "🔔",
+	}
+}
+
+// reasonAndTimeLine explains why the notification was sent and when its
+// thread was updated.
+func (c *MyPlugin) reasonAndTimeLine(n GithubNotification) string {
+	reason, ok := c.lookup("reason." + n.Reason)
+	if !ok {
+		reason = strings.ReplaceAll(n.Reason, "_", " ")
+	}
+	updated := c.translate("notification.updated", c.formatTime(n.UpdatedAt))
+	if reason == "" {
+		return updated
+	}
+	return reason + " · " + updated
+}
+
+// formatTime renders t in the configured timezone.
+func (c *MyPlugin) formatTime(t time.Time) string {
+	location := c.location
+	if location == nil {
+		location = time.UTC
+	}
+	return t.In(location).Format("2006-01-02 15:04 MST")
+}
+
+func (c *MyPlugin) withReasonMarker(reason, message string) string {
+	if marker := c.reasonMarkers[reason]; marker != "" {
+		return marker + " " + message
+	}
+	return message
+}
+
+// validateRepoPattern checks an owner/repo key that may use the wildcards of
+// path.Match, e.g. "experiments/*".
+func validateRepoPattern(pattern string) error {
+	owner, name, ok := strings.Cut(pattern, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("%q is not of the form owner/repo", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%q: %w", pattern, err)
+	}
+	return nil
+}
+
+// repoMuted is the repoPriorities value of a repo whose notifications are
+// marked seen without being sent.
+const repoMuted = -1
+
+// repoPriority returns the configured priority for repo, which may be
+// repoMuted. An exact entry wins over patterns; among matching patterns the
+// most specific one, i.e. the one with the most literal characters, wins.
+func (c *MyPlugin) repoPriority(repo string) (int, bool) {
+	if priority, ok := c.repoPriorities[repo]; ok {
+		return priority, true
+	}
+	best, bestScore, found := 0, -1, false
+	for pattern, priority := range c.repoPriorities {
+		if !matchRepo(pattern, repo) {
+			continue
+		}
+		score := len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
+		if score > bestScore || (score == bestScore && priority > best) {
+			best, bestScore, found = priority, score, true
+		}
+	}
+	return best, found
+}
+
+// subjectNumber returns the issue or pull request number at the end of a
+// subject API URL, or "" when the subject is not numbered.
+func subjectNumber(apiURL string) string {
+	last := path.Base(apiURL)
+	if _, err := strconv.Atoi(last); err != nil {
+		return ""
+	}
+	return last
+}
+
+// subjectWebURL turns the API URL of a notification subject into the page
+// a browser should open. Releases are addressed by ID in the API, so they
+// link to the release list; subjects without a URL link to the repo's
+// discussions or to the repo itself.
+func (c *MyPlugin) subjectWebURL(n GithubNotification) string {
+	repo := n.Repository.FullName
+	_, rest, ok := strings.Cut(n.Subject.URL, "/repos/"+repo+"/")
+	if !ok {
+		if n.Subject.Type == "Discussion" {
+			return c.webURL(repo + "/discussions")
+		}
+		return c.webURL(repo)
+	}
+	kind, id, _ := strings.Cut(rest, "/")
+	switch kind {
+	case "issues", "discussions":
+		return c.webURL(repo + "/" + kind + "/" + id)
+	case "pulls":
+		return c.webURL(repo + "/pull/" + id)
+	case "commits":
+		return c.webURL(repo + "/commit/" + id)
+	case "releases":
+		return c.webURL(repo + "/releases")
+	}
+	return c.webURL(repo)
+}
+
+// formatNotification builds the title and message of a notification. A
+// messageTemplate replaces the message built from format and titleSource;
+// otherwise useMarkdown does.
+func (c *MyPlugin) formatNotification(n GithubNotification, typeLabel string) (title, message string) {
+	name := c.typeName(typeLabel)
+	number := subjectNumber(n.Subject.URL)
+	if n.Subject.Type != "Issue" && n.Subject.Type != "PullRequest" {
+		number = ""
+	}
+	title, message = c.formatBuiltin(n, name, number)
+	switch {
+	case c.messageTmpl != nil:
+		message = c.render(c.messageTmpl, c.templateData(n, name, number), message)
+	case c.useMarkdown:
+		message = c.markdownMessage(n, name, number)
+	}
+	return title, message
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// markdownMessage renders a notification as a bold link to the repo followed
+// by a link to the subject, e.g.
+// "**[octocat/hello-world](…)** PR #42: [Fix the thing](…)".
+func (c *MyPlugin) markdownMessage(n GithubNotification, name, number string) string {
+	label := name
+	if number != "" {
+		label += " #" + number
+	}
+	repo := n.Repository.FullName
+	return fmt.Sprintf("**[%s](%s)** %s: [%s](%s)",
+		markdownEscaper.Replace(repo), c.webURL(repo), label,
+		markdownEscaper.Replace(n.Subject.Title), c.subjectWebURL(n))
+}
+
+func (c *MyPlugin) formatBuiltin(n GithubNotification, name, number string) (title, message string) {
+	if c.format == formatCompact {
+		ref := n.Repository.FullName
+		if number != "" {
+			ref += " #" + number
+		}
+		line := fmt.Sprintf("%s %s: %s", ref, name, n.Subject.Title)
+		switch c.titleSource {
+		case titleRepo:
+			return n.Repository.FullName, line
+		case titleCustom:
+			return c.expandTitle(n, name, number), line
+		}
+		return "", line
+	}
+	subject := c.typePrefix(n.Subject.Type, name) + " " + n.Subject.Title
+	switch c.titleSource {
+	case titleRepo:
+		return n.Repository.FullName, subject
+	case titleCustom:
+		return c.expandTitle(n, name, number), c.translate("notification.in", subject, n.Repository.FullName)
+	}
+	return subject, c.translate("notification.body", name, n.Repository.FullName)
+}
+
+// expandTitle fills in the placeholders of titleTemplate. A "#{number}" with
+// no number to substitute is dropped along with the "#". A titleTemplate in
+// text/template syntax is executed instead.
+func (c *MyPlugin) expandTitle(n GithubNotification, typeLabel, number string) string {
+	if c.titleTmpl != nil {
+		return c.render(c.titleTmpl, c.templateData(n, typeLabel, number), fmt.Sprintf("[%s] %s", typeLabel, n.Subject.Title))
+	}
+	tmpl := c.titleTemplate
+	if number == "" {
+		tmpl = strings.ReplaceAll(tmpl, "#{number}", "")
+	}
+	return strings.TrimSpace(strings.NewReplacer(
+		"{repo}", n.Repository.FullName,
+		"{type}", typeLabel,
+		"{title}", n.Subject.Title,
+		"{number}", number,
+	).Replace(tmpl))
+}
+
+// appendDetail adds an extra line to a message body, keeping compact
+// messages on a single line.
+func (c *MyPlugin) appendDetail(message, detail string) string {
+	if c.format == formatCompact {
+		return message + " · " + detail
+	}
+	if c.useMarkdown {
+		// A single line break would join the lines into one paragraph.
+		return message + "\n\n" + detail
+	}
+	return message + "\n" + detail
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactFormat(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"format": "compact"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "", msgs[0].Title)
+	assert.Equal(t, "octocat/hello-world #42 PR: Fix the thing", msgs[0].Message)
+	assert.Equal(t, srv.URL+"/octocat/hello-world/pull/42",
+		msgs[0].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+}
+
+func TestFormatValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "format": "fancy"}))
+}
+
+func TestSubjectNumber(t *testing.T) {
+	assert.Equal(t, "42", subjectNumber("https://api.github.com/repos/o/r/pulls/42"))
+	assert.Equal(t, "", subjectNumber("https://api.github.com/repos/o/r/releases/tags/v1"))
+	assert.Equal(t, "", subjectNumber(""))
+}
+
+func TestTitleSource(t *testing.T) {
+	pr := GithubNotification{}
+	pr.Repository.FullName = "octocat/hello-world"
+	pr.Subject.Title = "Fix the thing"
+	pr.Subject.Type = "PullRequest"
+	pr.Subject.URL = "https://api.github.com/repos/octocat/hello-world/pulls/42"
+
+	p := &MyPlugin{titleSource: titleRepo}
+	title, message := p.formatNotification(pr, "PR")
+	assert.Equal(t, "octocat/hello-world", title)
+	assert.Equal(t, "[PR] Fix the thing", message)
+
+	p = &MyPlugin{titleSource: titleCustom, titleTemplate: "{repo} #{number}"}
+	title, message = p.formatNotification(pr, "PR")
+	assert.Equal(t, "octocat/hello-world #42", title)
+	assert.Equal(t, "[PR] Fix the thing in octocat/hello-world", message)
+
+	release := pr
+	release.Subject.Type = "Release"
+	release.Subject.URL = "https://api.github.com/repos/octocat/hello-world/releases/1"
+	title, _ = p.formatNotification(release, "Release")
+	assert.Equal(t, "octocat/hello-world", title)
+
+	p = &MyPlugin{format: formatCompact, titleSource: titleRepo}
+	title, message = p.formatNotification(pr, "PR")
+	assert.Equal(t, "octocat/hello-world", title)
+	assert.Equal(t, "octocat/hello-world #42 PR: Fix the thing", message)
+}
+
+func TestTitleSourceValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "titleSource": "body"}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "titleSource": "custom", "titleTemplate": " "}))
+	assert.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "titleSource": "custom", "titleTemplate": "{type}: {title}"}))
+}
+
+func TestReasonMarkers(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"showReasonMarkers": true,
+		"reasonMarkers":     map[string]interface{}{"subscribed": "★"},
+	})
+	assert.Equal(t, "@", p.reasonMarkers["mention"], "overrides keep the other defaults")
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "👀 New PR notification in octocat/hello-world", msgs[0].Message)
+
+	p.reasonMarkers["review_requested"] = ""
+	assert.Equal(t, "body", p.withReasonMarker("review_requested", "body"))
+	assert.Equal(t, "★ body", p.withReasonMarker("subscribed", "body"))
+}
+
+func TestRepoPriority(t *testing.T) {
+	p := &MyPlugin{repoPriorities: map[string]int{
+		"octocat/critical": 8,
+		"experiments/*":    1,
+		"experiments/keep": 5,
+		"*/*":              3,
+		"octocat/crit*":    6,
+	}}
+	for repo, want := range map[string]int{
+		"octocat/critical": 8,
+		"octocat/critter":  6,
+		"experiments/foo":  1,
+		"experiments/keep": 5,
+		"someone/else":     3,
+	} {
+		got, ok := p.repoPriority(repo)
+		assert.True(t, ok, repo)
+		assert.Equal(t, want, got, repo)
+	}
+	_, ok := (&MyPlugin{}).repoPriority("octocat/hello-world")
+	assert.False(t, ok)
+}
+
+func TestRepoPrioritiesConfig(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"repoPriorities": map[string]interface{}{"octocat/*": 7}})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 7, msgs[0].Priority)
+}
+
+func repoNotificationJSON(id, repo string) string {
+	return fmt.Sprintf(`{"id":%q,"repository":{"full_name":%q},`+
+		`"subject":{"title":"T%s","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}`, id, repo, id)
+}
+
+func TestRepoPrioritiesMute(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"repoPriorities": map[string]interface{}{"octocat/*": 3, "octocat/noisy": -1, "octocat/critical": 8},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+repoNotificationJSON("1", "octocat/noisy")+`,`+
+		repoNotificationJSON("2", "octocat/critical")+`,`+repoNotificationJSON("3", "octocat/other")+`]`))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "[Issue] T2", msgs[0].Title)
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.Equal(t, "[Issue] T3", msgs[1].Title)
+	assert.Equal(t, 3, msgs[1].Priority)
+	assert.True(t, p.seenNotifications.has("1"), "a muted notification is marked seen")
+}
+
+func TestRepoPrioritiesValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoPriorities": map[string]interface{}{"octocat": 5}}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoPriorities": map[string]interface{}{"octocat/[": 5}}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoPriorities": map[string]interface{}{"octocat/x": 11}}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoPriorities": map[string]interface{}{"octocat/x": -2}}))
+	assert.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoPriorities": map[string]interface{}{"octocat/*": -1}}))
+}
+
+func TestSubjectWebURL(t *testing.T) {
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	notification := func(typ, apiURL string) GithubNotification {
+		var n GithubNotification
+		n.Repository.FullName = "octocat/hello-world"
+		n.Subject.Type = typ
+		n.Subject.URL = apiURL
+		return n
+	}
+	api := "https://api.github.com/repos/octocat/hello-world/"
+	for _, tc := range []struct {
+		n    GithubNotification
+		want string
+	}{
+		{notification("Issue", api+"issues/42"), "https://github.com/octocat/hello-world/issues/42"},
+		{notification("PullRequest", api+"pulls/7"), "https://github.com/octocat/hello-world/pull/7"},
+		{notification("Release", api+"releases/12345"), "https://github.com/octocat/hello-world/releases"},
+		{notification("Discussion", api+"discussions/3"), "https://github.com/octocat/hello-world/discussions/3"},
+		{notification("Discussion", ""), "https://github.com/octocat/hello-world/discussions"},
+		{notification("Commit", api+"commits/abc123"), "https://github.com/octocat/hello-world/commit/abc123"},
+		{notification("RepositoryVulnerabilityAlert", ""), "https://github.com/octocat/hello-world"},
+	} {
+		assert.Equal(t, tc.want, p.subjectWebURL(tc.n), tc.n.Subject.URL)
+	}
+
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apiBaseURL": "https://ghe.example.com/api/v3"}))
+	n := notification("Issue", "https://ghe.example.com/api/v3/repos/octocat/hello-world/issues/42")
+	assert.Equal(t, "https://ghe.example.com/octocat/hello-world/issues/42", p.subjectWebURL(n))
+}
+
+func TestTypePriorities(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"watchStars":     true,
+		"typePriorities": map[string]int{"Release": 7, "CheckSuite": 9, "star": 1},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	notification := func(id, typ string) string {
+		return fmt.Sprintf(`{"id":%q,"repository":{"full_name":"octocat/hello-world"},`+
+			`"subject":{"title":"T%s","type":%q,"url":""},"updated_at":"2024-05-01T10:00:00Z"}`, id, id, typ)
+	}
+	srv.handle("/notifications", serveJSON("["+notification("1", "Issue")+","+notification("2", "Release")+","+
+		notification("3", "CheckSuite")+","+notification("4", "RepositoryInvitation")+"]"))
+	srv.handle("/repos/octocat/hello-world/stargazers", serveJSON(`[{"starred_at":"2024-05-01T11:00:00Z","user":{"login":"bob"}}]`))
+	p.checkNotifications()
+	p.checkStars()
+
+	priorities := map[string]int{}
+	for _, msg := range rec.Messages() {
+		priorities[msg.Title] = msg.Priority
+	}
+	assert.Equal(t, map[string]int{
+		"[Issue] T1":                2, // default kept when only other types are overridden
+		"[Release] T2":              7,
+		"[CheckSuite] T3":           9,
+		"[RepositoryInvitation] T4": 2, // unknownTypePriority
+		"New Star":                  1,
+	}, priorities)
+}
+
+func TestTypePriorityRange(t *testing.T) {
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "typePriorities": map[string]int{"Issue": 11}})
+	assert.ErrorContains(t, err, "typePriorities")
+}
+
+func TestMarkdownMessages(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"useMarkdown": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	n := GithubNotification{ID: "1"}
+	n.Repository.FullName = "octocat/hello-world"
+	n.Subject.Title = "Support *bold* [links]"
+	n.Subject.Type = "PullRequest"
+	n.Subject.URL = srv.URL + "/repos/octocat/hello-world/pulls/42"
+	p.sendNotification(n, "PR", 2, "from @alice")
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[PR] Support *bold* [links]", msgs[0].Title, "titles stay plain text")
+	assert.Equal(t, "**[octocat/hello-world]("+srv.URL+"/octocat/hello-world)** PR #42: "+
+		`[Support \*bold\* \[links\]](`+srv.URL+"/octocat/hello-world/pull/42)\n\nfrom @alice", msgs[0].Message)
+	assert.Equal(t, map[string]interface{}{"contentType": "text/markdown"}, msgs[0].Extras["client::display"])
+
+	p.useMarkdown = false
+	p.sendNotification(n, "PR", 2)
+	msgs = rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.NotContains(t, msgs[1].Extras, "client::display", "plain text unless enabled")
+}
+
+func TestReasonAndTimeLine(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"showReasonAndTime": true, "timezone": "Europe/Berlin"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+reasonNotificationJSON("1", "review_requested")+`]`))
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New Issue notification in octocat/hello-world\nyour review was requested · updated 2024-05-01 12:00 CEST", msgs[0].Message)
+
+	n := GithubNotification{Reason: "some_new_reason", UpdatedAt: time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)}
+	assert.Equal(t, "some new reason · updated 2024-01-15 10:30 CET", p.reasonAndTimeLine(n))
+	p.language = "de"
+	n.Reason = "mention"
+	assert.Equal(t, "du wurdest erwähnt · aktualisiert 2024-01-15 10:30 CET", p.reasonAndTimeLine(n))
+}
+
+func TestTimezone(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-05-01 10:00 UTC", p.formatTime(at), "UTC by default")
+
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "timezone": "America/New_York"}))
+	assert.Equal(t, "2024-05-01 06:00 EDT", p.formatTime(at))
+
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "timezone": "Mars/Olympus_Mons"}))
+}
+
+func TestRateLimitAlertUsesTimezone(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"timezone": "Asia/Tokyo", "notifyOnError": true})
+	reset := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	p.handlePollError(&fetchError{Kind: errorKindRateLimit, StatusCode: 403, ResetAt: reset, Err: errors.New("unexpected status 403 Forbidden")})
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0].Title, "polling paused until 2024-05-01 19:00 JST")
+}
+
+func TestUseEmoji(t *testing.T) {
+	p := &MyPlugin{useEmoji: true}
+	for subjectType, want := range map[string]string{
+		"Issue":       "🐛 Crash on start",
+		"PullRequest": "🔀 Crash on start",
+		"Release":     "🏷️ Crash on start",
+		"Discussion":  "💬 Crash on start",
+		"CheckSuite":  "[CheckSuite] Crash on start",
+	} {
+		n := GithubNotification{}
+		n.Subject.Type = subjectType
+		n.Subject.Title = "Crash on start"
+		label, _ := notificationLabel(subjectType)
+		title, _ := p.formatNotification(n, label)
+		assert.Equal(t, want, title, subjectType)
+	}
+	assert.Equal(t, "⭐ New Star", p.withTypeEmoji("star", p.translate("star.title")))
+
+	p.useEmoji = false
+	n := GithubNotification{}
+	n.Subject.Type = "PullRequest"
+	n.Subject.Title = "Crash on start"
+	title, _ := p.formatNotification(n, "PR")
+	assert.Equal(t, "[PR] Crash on start", title)
+	assert.Equal(t, "New Star", p.withTypeEmoji("star", p.translate("star.title")))
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Repo struct {
+	FullName      string `json:"full_name"`
+	HasWiki       bool   `json:"has_wiki"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// Token schemes select the Authorization prefix. Classic tokens work with
+// both, fine-grained and GitHub App tokens need Bearer on some endpoints.
+const (
+	// tokenSchemeAuto uses Bearer for fine-grained (github_pat_) and GitHub
+	// App (ghs_, ghu_) tokens and token for everything else.
+	tokenSchemeAuto   = "auto"
+	tokenSchemeToken  = "token"
+	tokenSchemeBearer = "bearer"
+)
+
+// authorization returns the Authorization header value for the token.
+func (c *MyPlugin) authorization() (string, error) {
+	if c.githubApp != nil {
+		token, err := c.installationToken()
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+	scheme := c.tokenScheme
+	if scheme == "" || scheme == tokenSchemeAuto {
+		scheme = tokenSchemeToken
+		for _, prefix := range []string{"github_pat_", "ghs_", "ghu_"} {
+			if strings.HasPrefix(c.githubToken, prefix) {
+				scheme = tokenSchemeBearer
+			}
+		}
+	}
+	if scheme == tokenSchemeBearer {
+		return "Bearer " + c.githubToken, nil
+	}
+	return "token " + c.githubToken, nil
+}
+
+// newGitHubRequest builds a GitHub API request with the headers every call
+// needs: the token, the Accept media type and the User-Agent. Requests with a
+// body are sent as JSON. ctx is normally c.requestContext(); requestTimeout
+// is enforced by the shared client.
+func (c *MyPlugin) newGitHubRequest(ctx context.Context, method, endpoint, accept string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := c.authorization()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Accept", accept)
+	ua := c.userAgent
+	if ua == "" {
+		ua = userAgent("")
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// parseProxyURL validates the proxyURL option; an empty value means none.
+func parseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+		return nil, fmt.Errorf("proxyURL %q must be an absolute http(s) or socks5 URL", raw)
+	}
+	return u, nil
+}
+
+// proxy selects the proxy of the shared client: proxyURL when configured,
+// the environment otherwise.
+func (c *MyPlugin) proxy() func(*http.Request) (*url.URL, error) {
+	if c.proxyURL == nil {
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(c.proxyURL)
+}
+
+// getJSON fetches a single GitHub API resource and decodes it into v.
+func (c *MyPlugin) getJSON(endpoint, accept string, v interface{}) error {
+	req, err := c.newGitHubRequest(c.requestContext(), "GET", endpoint, accept, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &fetchError{Kind: errorKindAPI, StatusCode: resp.StatusCode, Endpoint: req.URL.Path,
+			RequestID: resp.Header.Get("X-GitHub-Request-Id"), Err: err}
+	}
+	return nil
+}
+
+// getJSONIfChanged is getJSON as a conditional request: *etag is sent as
+// If-None-Match and updated from the response. It returns false without
+// touching v when GitHub answers 304 Not Modified, which does not count
+// against the rate limit. next is the rel="next" link of a paginated
+// response.
+func (c *MyPlugin) getJSONIfChanged(endpoint, accept string, etag *string, v interface{}) (changed bool, next string, err error) {
+	req, err := c.newGitHubRequest(c.requestContext(), "GET", endpoint, accept, nil)
+	if err != nil {
+		return false, "", err
+	}
+	if *etag != "" {
+		req.Header.Add("If-None-Match", *etag)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, "", responseError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, "", &fetchError{Kind: errorKindAPI, StatusCode: resp.StatusCode, Endpoint: req.URL.Path,
+			RequestID: resp.Header.Get("X-GitHub-Request-Id"), Err: err}
+	}
+	*etag = resp.Header.Get("ETag")
+	if m := linkNextRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		next = m[1]
+	}
+	return true, next, nil
+}
+
+func (c *MyPlugin) fetchUserRepos() ([]Repo, error) {
+	if c.githubApp != nil {
+		return c.fetchInstallationRepos()
+	}
+	endpoint := c.baseURL + "/user/repos?per_page=100&affiliation=" + url.QueryEscape(c.repoAffiliation)
+	return fetchAllPages[Repo](c, endpoint, "application/vnd.github.v3+json")
+}
+
+// watchedRepos returns the user's repositories plus those of every
+// configured organization, without duplicates. An organization the token
+// cannot list is logged and skipped.
+func (c *MyPlugin) watchedRepos() ([]Repo, error) {
+	repos, err := c.fetchUserRepos()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		seen[repo.FullName] = true
+	}
+	for _, org := range c.orgs {
+		orgRepos, err := fetchAllPages[Repo](c, fmt.Sprintf("%s/orgs/%s/repos?per_page=100", c.baseURL, org), "application/vnd.github.v3+json")
+		if err != nil {
+			c.recordError(fmt.Sprintf("listing repos of org %s (the token may lack read:org access)", org), err)
+			continue
+		}
+		for _, repo := range orgRepos {
+			if !seen[repo.FullName] {
+				seen[repo.FullName] = true
+				repos = append(repos, repo)
+			}
+		}
+	}
+	return repos, nil
+}
+
+// webURL builds a link into the GitHub web UI that belongs to the configured
+// API. GitHub Enterprise serves the API at https://host/api/v3 and the UI at
+// https://host.
+func (c *MyPlugin) webURL(path string) string {
+	base := "https://github.com"
+	if u, err := url.Parse(c.baseURL); err == nil && u.Host != "api.github.com" {
+		u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/api/v3"), "/")
+		base = u.String()
+	}
+	return base + "/" + strings.TrimPrefix(path, "/")
+}
+
+// splitList splits a comma-separated config value, dropping blanks.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseRepoList splits a comma-separated list of owner/repo names, rejecting
+// malformed entries and wildcards.
+func parseRepoList(option, value string) ([]string, error) {
+	repos := splitList(value)
+	for _, repo := range repos {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok || owner == "" || name == "" || strings.ContainsAny(name, "/*?[") || strings.ContainsAny(owner, "*?[") {
+			return nil, fmt.Errorf("%s entry %q is not of the form owner/repo", option, repo)
+		}
+	}
+	return repos, nil
+}
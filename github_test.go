@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchedReposIncludesOrgRepos(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"},{"full_name":"acme/shared"}]`))
+	srv.handle("/orgs/acme/repos", serveJSON(`[{"full_name":"acme/shared"},{"full_name":"acme/tools"}]`))
+
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"orgs": "acme, forbidden"})
+	repos, err := p.watchedRepos()
+	require.NoError(t, err)
+
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.FullName)
+	}
+	assert.Equal(t, []string{"octocat/hello-world", "acme/shared", "acme/tools"}, names)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitList(" a, ,b ,"))
+	assert.Nil(t, splitList(""))
+}
+
+func TestRequestsShareOneClientConnection(t *testing.T) {
+	var mu sync.Mutex
+	states := map[http.ConnState]int{}
+	srv := newUnstartedFixtureServer(t)
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		mu.Lock()
+		defer mu.Unlock()
+		states[state]++
+	}
+	srv.Start()
+	count := func(state http.ConnState) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return states[state]
+	}
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	srv.serveFixture("/user/repos", "user_repos.json")
+	srv.serveFixture("/repos/octocat/hello-world/stargazers", "stargazers_initial.json")
+
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "watchStars": true})
+	client := p.client
+	require.NoError(t, p.Enable())
+	p.checkNotifications()
+	p.checkStars()
+	p.checkNotifications()
+	assert.Same(t, client, p.client)
+	assert.Less(t, count(http.StateNew), count(http.StateActive), "requests reuse keepalive connections")
+
+	require.NoError(t, p.Disable())
+	assert.Eventually(t, func() bool { return count(http.StateClosed) == count(http.StateNew) }, time.Second, 10*time.Millisecond,
+		"Disable closes idle connections")
+}
+
+func TestUserReposFollowPaginationWithAffiliation(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON(`[{"full_name":"octocat/second"}]`)(w, r)
+			return
+		}
+		w.Header().Set("Link", "<"+srv.URL+`/user/repos?page=2>; rel="next"`)
+		serveJSON(`[{"full_name":"octocat/first"}]`)(w, r)
+	})
+	srv.handle("/repos/octocat/first/stargazers", serveJSON(`[]`))
+	srv.handle("/repos/octocat/second/stargazers", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "repoAffiliation": "owner"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/repos/octocat/second/stargazers", serveJSON(`[{"starred_at":"2024-05-01T11:00:00Z","user":{"login":"bob"}}]`))
+	p.checkStars()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Repo octocat/second received a star from bob", msgs[0].Message)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, queries, "per_page=100&affiliation=owner")
+}
+
+func TestRepoAffiliationValidation(t *testing.T) {
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoAffiliation": "owner,member"}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "repoAffiliation": ""}))
+}
+
+func TestWebURLFollowsAPIBaseURL(t *testing.T) {
+	for base, want := range map[string]string{
+		"https://api.github.com":              "https://github.com/octocat/hello-world",
+		"https://github.mycorp.com/api/v3":    "https://github.mycorp.com/octocat/hello-world",
+		"https://ghe.example.org/api/v3/":     "https://ghe.example.org/octocat/hello-world",
+		"http://localhost:8080/github/api/v3": "http://localhost:8080/github/octocat/hello-world",
+	} {
+		p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+		require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "apiBaseURL": base}))
+		assert.Equal(t, want, p.webURL("octocat/hello-world"), base)
+	}
+}
+
+func TestEnterpriseBaseURLIsUsedForRequests(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := newFixtureServer(t)
+	for _, path := range []string{"/api/v3/notifications", "/api/v3/user/repos", "/api/v3/repos/octocat/hello-world/stargazers"} {
+		srv.handle(path, func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			mu.Unlock()
+			if r.URL.Path == "/api/v3/user/repos" {
+				serveJSON(`[{"full_name":"octocat/hello-world"}]`)(w, r)
+				return
+			}
+			serveJSON(`[]`)(w, r)
+		})
+	}
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"apiBaseURL": srv.URL + "/api/v3/", "watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/api/v3/notifications", "/api/v3/user/repos", "/api/v3/repos/octocat/hello-world/stargazers"}, paths)
+	assert.Equal(t, srv.URL+"/octocat/hello-world", p.webURL("octocat/hello-world"))
+}
+
+func TestRequestsCarryUserAgent(t *testing.T) {
+	var mu sync.Mutex
+	var agents []string
+	srv := newFixtureServer(t)
+	record := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			agents = append(agents, r.Header.Get("User-Agent"))
+			mu.Unlock()
+			serveJSON(body)(w, r)
+		}
+	}
+	srv.handle("/notifications", record(`[]`))
+	srv.handle("/user/repos", record(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", record(`[]`))
+
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "userAgentSuffix": "home-server"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, agents)
+	for _, agent := range agents {
+		assert.Equal(t, "gotify-github-plugin/1.0.0 home-server", agent)
+	}
+}
+
+func TestNewGitHubRequestHeaders(t *testing.T) {
+	p := &MyPlugin{githubToken: "secret", userAgent: "gotify-github-plugin/1.0.0 home"}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := p.newGitHubRequest(ctx, "GET", "https://api.github.com/notifications", "application/vnd.github.star+json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token secret", req.Header.Get("Authorization"))
+	assert.Equal(t, "application/vnd.github.star+json", req.Header.Get("Accept"))
+	assert.Equal(t, "gotify-github-plugin/1.0.0 home", req.Header.Get("User-Agent"))
+	assert.Empty(t, req.Header.Get("Content-Type"))
+	assert.Equal(t, ctx, req.Context())
+
+	req, err = p.newGitHubRequest(ctx, "PUT", "https://api.github.com/notifications", "application/vnd.github.v3+json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+}
+
+func TestAuthorizationScheme(t *testing.T) {
+	cases := []struct {
+		scheme, token, want string
+	}{
+		{tokenSchemeAuto, "ghp_classic", "token ghp_classic"},
+		{tokenSchemeAuto, "github_pat_fine", "Bearer github_pat_fine"},
+		{tokenSchemeAuto, "ghs_installation", "Bearer ghs_installation"},
+		{tokenSchemeToken, "github_pat_fine", "token github_pat_fine"},
+		{tokenSchemeBearer, "ghp_classic", "Bearer ghp_classic"},
+	}
+	for _, tc := range cases {
+		p := &MyPlugin{githubToken: tc.token, tokenScheme: tc.scheme}
+		req, err := p.newGitHubRequest(context.Background(), "GET", "https://api.github.com/user", "application/json", nil)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, req.Header.Get("Authorization"), "%s with %s", tc.scheme, tc.token)
+	}
+}
+
+func TestUnknownTokenSchemeIsRejected(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	conf := p.DefaultConfig().(*Config)
+	conf.Token = "secret"
+	conf.TokenScheme = "basic"
+	assert.ErrorContains(t, p.ValidateAndSetConfig(conf), "tokenScheme")
+}
+
+func TestUserAgentWithoutSuffix(t *testing.T) {
+	assert.Regexp(t, `^gotify-github-plugin/\d+\.\d+\.\d+$`, userAgent(""))
+}
+
+func TestRequestsGoThroughConfiguredProxy(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	proxy := newFixtureServer(t)
+	proxy.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.Host)
+		mu.Unlock()
+		serveJSON(`[]`)(w, r)
+	})
+
+	p, _ := newTestPlugin(t, proxy, map[string]interface{}{
+		"apiBaseURL": "http://github.invalid",
+		"proxyURL":   proxy.URL,
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, hosts)
+	assert.Equal(t, "github.invalid", hosts[0])
+}
+
+func TestInvalidProxyURLIsRejected(t *testing.T) {
+	_, err := parseProxyURL("ftp://proxy.example.com")
+	assert.Error(t, err)
+	_, err = parseProxyURL("proxy.example.com:3128")
+	assert.Error(t, err)
+	u, err := parseProxyURL("http://proxy.example.com:3128")
+	require.NoError(t, err)
+	assert.Equal(t, "proxy.example.com:3128", u.Host)
+}
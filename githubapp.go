@@ -0,0 +1,167 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// installationTokenMargin is how long before its expiry an installation
+// token is replaced, so no request goes out with a token about to expire.
+const installationTokenMargin = 5 * time.Minute
+
+// githubApp authenticates as an installation of a GitHub App. It signs a
+// short-lived JWT with the app's private key and exchanges it for an
+// installation access token, which is valid for an hour and cached until
+// shortly before it expires.
+type githubApp struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// parseGitHubApp returns nil when no GitHub App is configured.
+func parseGitHubApp(conf Config) (*githubApp, error) {
+	if conf.GitHubAppID == 0 && conf.GitHubInstallationID == 0 && conf.GitHubAppPrivateKey == "" {
+		return nil, nil
+	}
+	if conf.GitHubAppID <= 0 || conf.GitHubInstallationID <= 0 || conf.GitHubAppPrivateKey == "" {
+		return nil, fmt.Errorf("githubAppID, githubInstallationID and githubAppPrivateKey must be set together")
+	}
+	block, _ := pem.Decode([]byte(conf.GitHubAppPrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("githubAppPrivateKey must be a PEM encoded private key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); err8 != nil || !ok {
+			return nil, fmt.Errorf("githubAppPrivateKey must be an RSA private key: %w", err)
+		}
+	}
+	return &githubApp{appID: conf.GitHubAppID, installationID: conf.GitHubInstallationID, key: key}, nil
+}
+
+// readsNotifications reports whether the credentials can read the user's
+// notifications. Installation tokens cannot, so they are never sent to
+// /notifications.
+func (c *MyPlugin) readsNotifications() bool {
+	return c.githubApp == nil
+}
+
+// equal reports whether a and b authenticate as the same installation.
+func (a *githubApp) equal(b *githubApp) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.appID == b.appID && a.installationID == b.installationID && a.key.Equal(b.key)
+}
+
+// jwt returns the app's RS256 token. It is backdated a minute to allow for
+// clock drift and expires within GitHub's ten minute limit.
+func (a *githubApp) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(a.appID),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// fetchInstallationRepos lists the repositories the installation was granted,
+// which take the place of /user/repos for an installation token.
+func (c *MyPlugin) fetchInstallationRepos() ([]Repo, error) {
+	var repos []Repo
+	next := c.baseURL + "/installation/repositories?per_page=100"
+	for page := 1; next != ""; page++ {
+		if page > c.maxPages {
+			c.warnTruncated(next)
+			break
+		}
+		var list struct {
+			Repositories []Repo `json:"repositories"`
+		}
+		var etag string
+		_, link, err := c.getJSONIfChanged(next, "application/vnd.github.v3+json", &etag, &list)
+		if err != nil {
+			return repos, err
+		}
+		repos = append(repos, list.Repositories...)
+		next = link
+	}
+	return repos, nil
+}
+
+// installationToken returns a valid installation access token, minting a new
+// one when there is none yet or the cached one is about to expire.
+func (c *MyPlugin) installationToken() (string, error) {
+	a := c.githubApp
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := c.clock.Now()
+	if a.token != "" && now.Before(a.expiresAt.Add(-installationTokenMargin)) {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(now)
+	if err != nil {
+		return "", fmt.Errorf("signing the GitHub App token: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/app/installations/%d/access_tokens", c.baseURL, a.installationID)
+	req, err := http.NewRequestWithContext(c.requestContext(), http.MethodPost, endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", responseError(resp)
+	}
+	var minted struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&minted); err != nil {
+		return "", fmt.Errorf("decoding installation token: %w", err)
+	}
+	if minted.Token == "" {
+		return "", fmt.Errorf("GitHub returned no installation token")
+	}
+	a.token, a.expiresAt = minted.Token, minted.ExpiresAt
+	c.redactor.setMinted(minted.Token)
+	c.infoLog("minted GitHub App installation token", "expires", c.formatTime(minted.ExpiresAt))
+	return a.token, nil
+}
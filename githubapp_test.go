@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubAppRefreshesInstallationTokenBeforeExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	clk := newFakeClock()
+	var mu sync.Mutex
+	minted := 0
+	var lastAuth string
+	srv := newFixtureServer(t)
+	srv.handle("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		if !assert.Len(t, parts, 3) {
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var c struct {
+			Iss string `json:"iss"`
+		}
+		assert.NoError(t, json.Unmarshal(claims, &c))
+		assert.Equal(t, "7", c.Iss)
+
+		mu.Lock()
+		minted++
+		n := minted
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"ghs_minted%d","expires_at":%q}`, n, clk.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("an installation token was sent to %s", r.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+	})
+	srv.handle("/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastAuth = r.Header.Get("Authorization")
+		mu.Unlock()
+		serveJSON(`{"total_count":1,"repositories":[{"full_name":"octocat/hello-world"}]}`)(w, r)
+	})
+	srv.handle("/repos/octocat/hello-world/actions/runs", serveJSON(`{"workflow_runs":[]}`))
+
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{
+		"token":                "",
+		"githubAppID":          7,
+		"githubInstallationID": 42,
+		"githubAppPrivateKey":  string(keyPEM),
+		"watchWorkflows":       true,
+		"watchUnreadCount":     true,
+	})
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.poll()
+	mu.Lock()
+	assert.Equal(t, 1, minted, "the token is reused while it is valid")
+	assert.Equal(t, "Bearer ghs_minted1", lastAuth)
+	mu.Unlock()
+
+	clk.Advance(54 * time.Minute)
+	p.poll()
+	mu.Lock()
+	assert.Equal(t, 1, minted)
+	mu.Unlock()
+
+	clk.Advance(2 * time.Minute)
+	p.poll()
+	mu.Lock()
+	assert.Equal(t, 2, minted, "the token is replaced five minutes before it expires")
+	assert.Equal(t, "Bearer ghs_minted2", lastAuth)
+	mu.Unlock()
+	assert.Equal(t, "ghs_minted1 ***", p.redactor.redact("ghs_minted1 ghs_minted2"),
+		"the new token takes the place of the old one")
+}
+
+func TestGitHubAppValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.ErrorContains(t, p.ValidateAndSetConfig(map[string]interface{}{"githubAppID": 7}), "must be set together")
+	assert.ErrorContains(t, p.ValidateAndSetConfig(map[string]interface{}{
+		"githubAppID": 7, "githubInstallationID": 42, "githubAppPrivateKey": "not a key",
+	}), "PEM")
+	assert.ErrorContains(t, p.ValidateAndSetConfig(map[string]interface{}{"token": ""}), "token is required")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotify/plugin-api"
+)
+
+// githubEvent holds the parts of a GitHub webhook payload the plugin reports.
+type githubEvent struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+	Issue       *githubEventItem `json:"issue"`
+	PullRequest *githubEventItem `json:"pull_request"`
+	Release     *struct {
+		Name    string `json:"name"`
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	} `json:"release"`
+}
+
+type githubEventItem struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	HTMLURL string `json:"html_url"`
+}
+
+// validGitHubSignature checks the X-Hub-Signature-256 header GitHub computes
+// over the raw body with the webhook secret.
+func validGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// handleGitHubEvent receives webhooks configured on a GitHub repository or
+// organization, so events arrive instantly instead of on the next poll.
+func (c *MyPlugin) handleGitHubEvent(ctx *gin.Context) {
+	_, _, secret := c.webhookSecrets()
+	if secret == "" {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "githubWebhookSecret is not configured"})
+		return
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unreadable body"})
+		return
+	}
+	if !validGitHubSignature(secret, body, ctx.GetHeader("X-Hub-Signature-256")) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	kind := ctx.GetHeader("X-GitHub-Event")
+	if kind == "ping" {
+		ctx.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+	var event githubEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	msg, ok := c.githubEventMessage(kind, event)
+	if !ok {
+		ctx.JSON(http.StatusAccepted, gin.H{"ignored": kind})
+		return
+	}
+	if err := c.msgHandler.SendMessage(msg); err != nil {
+		c.recordError(fmt.Sprintf("sending %s webhook event", kind), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "sending the message failed"})
+		return
+	}
+	c.infoLog("sent webhook event", "event", kind, "repo", event.Repository.FullName)
+	ctx.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// githubEventMessage turns a webhook event into a message. Events and
+// actions the plugin does not report return false.
+func (c *MyPlugin) githubEventMessage(kind string, event githubEvent) (plugin.Message, bool) {
+	repo := event.Repository.FullName
+	var title, body, url, typ string
+	switch {
+	case kind == "star" && event.Action == "created":
+		// Mark the star seen so the next poll does not report it again.
+		c.pollMu.Lock()
+		if c.seenStars != nil {
+			c.seenStars.mark(starKey(repo, event.Sender.Login), c.clock.Now())
+		}
+		c.pollMu.Unlock()
+		typ = "star"
+		title = c.withTypeEmoji("star", c.translate("star.title"))
+		body = c.translate("star.body", repo, event.Sender.Login)
+		url = event.Repository.HTMLURL
+	case kind == "issues" && event.Action == "opened" && event.Issue != nil:
+		typ = "Issue"
+		title = c.typePrefix("Issue", c.typeName("Issue")) + " " + event.Issue.Title
+		body = fmt.Sprintf("%s opened %s#%d", event.Sender.Login, repo, event.Issue.Number)
+		url = event.Issue.HTMLURL
+	case kind == "pull_request" && event.Action == "opened" && event.PullRequest != nil:
+		typ = "PullRequest"
+		title = c.typePrefix("PullRequest", c.typeName("PR")) + " " + event.PullRequest.Title
+		body = fmt.Sprintf("%s opened %s#%d", event.Sender.Login, repo, event.PullRequest.Number)
+		url = event.PullRequest.HTMLURL
+	case kind == "release" && event.Action == "published" && event.Release != nil:
+		name := event.Release.Name
+		if name == "" {
+			name = event.Release.TagName
+		}
+		typ = "Release"
+		title = c.typePrefix("Release", c.typeName("Release")) + " " + name
+		body = fmt.Sprintf("%s published %s in %s", event.Sender.Login, event.Release.TagName, repo)
+		url = event.Release.HTMLURL
+	default:
+		return plugin.Message{}, false
+	}
+	return plugin.Message{
+		Title:    title,
+		Message:  body,
+		Priority: c.typePriority(typ),
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": url,
+				},
+			},
+		},
+	}, true
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedGitHubEvent(secret, event, body string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, "/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+const starEvent = `{"action":"created","repository":{"full_name":"octocat/hello-world",` +
+	`"html_url":"https://github.com/octocat/hello-world"},"sender":{"login":"bob"}}`
+
+func TestGitHubWebhookStarEvent(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"githubWebhookSecret": "hook-secret", "watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	r := newWebhookRouter(p)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, signedGitHubEvent("hook-secret", "star", starEvent))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New Star", msgs[0].Title)
+	assert.Equal(t, "Repo octocat/hello-world received a star from bob", msgs[0].Message)
+
+	srv.handle("/repos/octocat/hello-world/stargazers", serveJSON(`[{"starred_at":"2024-05-01T11:00:00Z","user":{"login":"bob"}}]`))
+	p.checkStars()
+	assert.Len(t, rec.Messages(), 1, "the poll must not report the star again")
+}
+
+func TestGitHubWebhookRejectsBadSignatures(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"githubWebhookSecret": "hook-secret"})
+	r := newWebhookRouter(p)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, signedGitHubEvent("wrong-secret", "star", starEvent))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := signedGitHubEvent("hook-secret", "star", starEvent)
+	req.Header.Del("X-Hub-Signature-256")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, rec.Messages())
+}
+
+func TestGitHubWebhookRequiresSecret(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	r := newWebhookRouter(p)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, signedGitHubEvent("", "star", starEvent))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGitHubWebhookEvents(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"githubWebhookSecret": "hook-secret"})
+	r := newWebhookRouter(p)
+	send := func(event, body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, signedGitHubEvent("hook-secret", event, body))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("ping", `{"zen":"Keep it simple."}`))
+	assert.Equal(t, http.StatusOK, send("pull_request", `{"action":"opened","repository":{"full_name":"octocat/hello-world"},`+
+		`"sender":{"login":"alice"},"pull_request":{"number":7,"title":"Add tests","html_url":"https://github.com/octocat/hello-world/pull/7"}}`))
+	assert.Equal(t, http.StatusAccepted, send("pull_request", `{"action":"closed","repository":{"full_name":"octocat/hello-world"}}`))
+	assert.Equal(t, http.StatusAccepted, send("fork", `{"repository":{"full_name":"octocat/hello-world"}}`))
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[PR] Add tests", msgs[0].Title)
+	assert.Equal(t, "alice opened octocat/hello-world#7", msgs[0].Message)
+	assert.Equal(t, "https://github.com/octocat/hello-world/pull/7",
+		msgs[0].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+}
+
+func TestGitHubWebhookShownInDisplay(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"githubWebhookSecret": "hook-secret"})
+	newWebhookRouter(p)
+	assert.Contains(t, p.GetDisplay(nil), "`"+p.webhookBasePath+"github`")
+}
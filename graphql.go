@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// graphQLURL derives the GraphQL endpoint from the REST base URL. GitHub
+// Enterprise serves REST at /api/v3 and GraphQL at /api/graphql.
+func (c *MyPlugin) graphQLURL() string {
+	if strings.HasSuffix(c.baseURL, "/api/v3") {
+		return strings.TrimSuffix(c.baseURL, "/v3") + "/graphql"
+	}
+	return c.baseURL + "/graphql"
+}
+
+// graphQL runs query with vars and decodes the "data" member into out.
+func (c *MyPlugin) graphQL(query string, vars map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := c.newGitHubRequest(c.requestContext(), "POST", c.graphQLURL(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.githubApp == nil {
+		req.Header.Set("Authorization", "bearer "+c.githubToken)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", result.Errors[0].Message)
+	}
+	return json.Unmarshal(result.Data, out)
+}
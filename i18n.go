@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// stringTables holds the bundled translations of message text. English is
+// the fallback for keys a table does not define.
+var stringTables = map[string]map[string]string{
+	"en": {
+		"type.Issue":              "Issue",
+		"type.PR":                 "PR",
+		"type.Release":            "Release",
+		"type.Discussion":         "Discussion",
+		"notification.body":       "New %s notification in %s",
+		"notification.in":         "%s in %s",
+		"star.title":              "New Star",
+		"star.body":               "Repo %s received a star from %s",
+		"unstar.title":            "Lost a Star",
+		"unstar.body":             "%s removed their star from %s",
+		"replay.detail":           "missed while the plugin was offline",
+		"escalate.title":          "Still unread after %s: %s",
+		"repogap.title":           "%d more updates in %s",
+		"digest.title":            "%d new notifications across %d repos",
+		"release.assetsReady":     "Assets are ready",
+		"release.assetsLate":      "Assets are not available yet",
+		"pull.opened":             "Opened",
+		"pull.closed":             "Closed",
+		"pull.merged":             "Merged",
+		"reason.assign":           "you were assigned",
+		"reason.author":           "you opened the thread",
+		"reason.comment":          "you commented",
+		"reason.ci_activity":      "a workflow run you triggered finished",
+		"reason.invitation":       "you were invited to the repository",
+		"reason.manual":           "you subscribed to the thread",
+		"reason.mention":          "you were mentioned",
+		"reason.review_requested": "your review was requested",
+		"reason.security_alert":   "a security alert was raised",
+		"reason.state_change":     "you changed the state",
+		"reason.subscribed":       "you are watching the repository",
+		"reason.team_mention":     "your team was mentioned",
+		"notification.updated":    "updated %s",
+		"escalate.body":           "%s notification in %s is still unread",
+		"repogap.body":            "%d notifications in %s were held back to avoid alert fatigue",
+		"unread.title":            "GitHub Inbox",
+		"unread.body":             "%d unread notifications",
+		"truncated.title":         "GitHub results truncated",
+		"truncated.body":          "Only the first %d pages of %s were read. Consider tightening your filters or raising maxPages.",
+		"ratelimit.title":         "GitHub rate limit running low",
+		"ratelimit.body":          "%s requests left until %s, polling less often until then",
+		"alert.network":           "GitHub unreachable",
+		"alert.auth":              "GitHub token rejected, polling paused until the configuration is updated",
+		"alert.rateLimit":         "GitHub rate limit exhausted, polling paused until %s",
+		"alert.api":               "GitHub API error",
+		"outage.title":            "GitHub appears to be having issues, polling paused",
+		"outage.body":             "GitHub keeps answering with server errors. Polling resumes automatically once it recovers. See https://www.githubstatus.com",
+		"outage.recovered":        "GitHub recovered, polling resumed",
+		"outage.recoveredBody":    "GitHub was unavailable for %s.",
+	},
+	"de": {
+		"type.Issue":              "Issue",
+		"type.PR":                 "PR",
+		"type.Release":            "Release",
+		"type.Discussion":         "Diskussion",
+		"notification.body":       "Neue %s-Benachrichtigung in %s",
+		"notification.in":         "%s in %s",
+		"star.title":              "Neuer Stern",
+		"star.body":               "Repo %s hat einen Stern von %s erhalten",
+		"unstar.title":            "Stern verloren",
+		"unstar.body":             "%s hat den Stern von %s entfernt",
+		"replay.detail":           "verpasst, während das Plugin offline war",
+		"escalate.title":          "Nach %s noch ungelesen: %s",
+		"repogap.title":           "%d weitere Updates in %s",
+		"digest.title":            "%d neue Benachrichtigungen in %d Repos",
+		"release.assetsReady":     "Assets sind verfügbar",
+		"release.assetsLate":      "Assets sind noch nicht verfügbar",
+		"pull.opened":             "Eröffnet",
+		"pull.closed":             "Geschlossen",
+		"pull.merged":             "Gemergt",
+		"reason.assign":           "dir zugewiesen",
+		"reason.author":           "du hast den Thread eröffnet",
+		"reason.comment":          "du hast kommentiert",
+		"reason.ci_activity":      "ein von dir ausgelöster Workflow ist fertig",
+		"reason.invitation":       "du wurdest zum Repository eingeladen",
+		"reason.manual":           "du hast den Thread abonniert",
+		"reason.mention":          "du wurdest erwähnt",
+		"reason.review_requested": "dein Review wurde angefragt",
+		"reason.security_alert":   "eine Sicherheitswarnung wurde ausgelöst",
+		"reason.state_change":     "du hast den Status geändert",
+		"reason.subscribed":       "du beobachtest das Repository",
+		"reason.team_mention":     "dein Team wurde erwähnt",
+		"notification.updated":    "aktualisiert %s",
+		"escalate.body":           "%s-Benachrichtigung in %s ist noch ungelesen",
+		"repogap.body":            "%d Benachrichtigungen in %s wurden zurückgehalten, um Alarmmüdigkeit zu vermeiden",
+		"unread.title":            "GitHub-Posteingang",
+		"unread.body":             "%d ungelesene Benachrichtigungen",
+		"truncated.title":         "GitHub-Ergebnisse gekürzt",
+		"truncated.body":          "Nur die ersten %d Seiten von %s wurden gelesen. Schränke die Filter ein oder erhöhe maxPages.",
+		"ratelimit.title":         "GitHub-Ratenlimit fast erreicht",
+		"ratelimit.body":          "%s Anfragen übrig bis %s, bis dahin wird seltener abgefragt",
+		"alert.network":           "GitHub nicht erreichbar",
+		"alert.auth":              "GitHub-Token abgelehnt, Abfragen pausiert, bis die Konfiguration aktualisiert wird",
+		"alert.rateLimit":         "GitHub-Ratenlimit erschöpft, Abfragen pausiert bis %s",
+		"alert.api":               "GitHub-API-Fehler",
+		"outage.title":            "GitHub scheint Probleme zu haben, Abfragen pausiert",
+		"outage.body":             "GitHub antwortet weiterhin mit Serverfehlern. Die Abfragen werden automatisch fortgesetzt, sobald GitHub wieder erreichbar ist. Siehe https://www.githubstatus.com",
+		"outage.recovered":        "GitHub ist wieder erreichbar, Abfragen fortgesetzt",
+		"outage.recoveredBody":    "GitHub war %s lang nicht verfügbar.",
+	},
+	"fr": {
+		"type.Issue":              "Issue",
+		"type.PR":                 "PR",
+		"type.Release":            "Version",
+		"type.Discussion":         "Discussion",
+		"notification.body":       "Nouvelle notification %s dans %s",
+		"notification.in":         "%s dans %s",
+		"star.title":              "Nouvelle étoile",
+		"star.body":               "Le dépôt %s a reçu une étoile de %s",
+		"unstar.title":            "Étoile perdue",
+		"unstar.body":             "%s a retiré son étoile de %s",
+		"replay.detail":           "manquée pendant que le plugin était hors ligne",
+		"escalate.title":          "Toujours non lu après %s : %s",
+		"repogap.title":           "%d autres mises à jour dans %s",
+		"digest.title":            "%d nouvelles notifications dans %d dépôts",
+		"release.assetsReady":     "Les fichiers sont disponibles",
+		"release.assetsLate":      "Les fichiers ne sont pas encore disponibles",
+		"pull.opened":             "Ouverte",
+		"pull.closed":             "Fermée",
+		"pull.merged":             "Fusionnée",
+		"reason.assign":           "vous avez été assigné",
+		"reason.author":           "vous avez ouvert le fil",
+		"reason.comment":          "vous avez commenté",
+		"reason.ci_activity":      "un workflow que vous avez lancé est terminé",
+		"reason.invitation":       "vous avez été invité au dépôt",
+		"reason.manual":           "vous êtes abonné au fil",
+		"reason.mention":          "vous avez été mentionné",
+		"reason.review_requested": "votre revue a été demandée",
+		"reason.security_alert":   "une alerte de sécurité a été levée",
+		"reason.state_change":     "vous avez changé l'état",
+		"reason.subscribed":       "vous suivez le dépôt",
+		"reason.team_mention":     "votre équipe a été mentionnée",
+		"notification.updated":    "mis à jour %s",
+		"escalate.body":           "La notification %s dans %s est toujours non lue",
+		"repogap.body":            "%d notifications dans %s ont été retenues pour éviter la surcharge d'alertes",
+		"unread.title":            "Boîte de réception GitHub",
+		"unread.body":             "%d notifications non lues",
+		"truncated.title":         "Résultats GitHub tronqués",
+		"truncated.body":          "Seules les %d premières pages de %s ont été lues. Resserrez vos filtres ou augmentez maxPages.",
+		"ratelimit.title":         "Limite de requêtes GitHub bientôt atteinte",
+		"ratelimit.body":          "%s requêtes restantes jusqu'à %s, interrogation moins fréquente d'ici là",
+		"alert.network":           "GitHub injoignable",
+		"alert.auth":              "Jeton GitHub refusé, interrogation suspendue jusqu'à la mise à jour de la configuration",
+		"alert.rateLimit":         "Limite de requêtes GitHub épuisée, interrogation suspendue jusqu'à %s",
+		"alert.api":               "Erreur de l'API GitHub",
+		"outage.title":            "GitHub semble rencontrer des problèmes, interrogation suspendue",
+		"outage.body":             "GitHub continue de répondre par des erreurs serveur. L'interrogation reprendra automatiquement dès son rétablissement. Voir https://www.githubstatus.com",
+		"outage.recovered":        "GitHub est rétabli, interrogation reprise",
+		"outage.recoveredBody":    "GitHub a été indisponible pendant %s.",
+	},
+	"es": {
+		"type.Issue":              "Issue",
+		"type.PR":                 "PR",
+		"type.Release":            "Versión",
+		"type.Discussion":         "Discusión",
+		"notification.body":       "Nueva notificación de %s en %s",
+		"notification.in":         "%s en %s",
+		"star.title":              "Nueva estrella",
+		"star.body":               "El repositorio %s recibió una estrella de %s",
+		"unstar.title":            "Estrella perdida",
+		"unstar.body":             "%s quitó su estrella de %s",
+		"replay.detail":           "perdida mientras el plugin estaba desconectado",
+		"escalate.title":          "Sin leer después de %s: %s",
+		"repogap.title":           "%d actualizaciones más en %s",
+		"digest.title":            "%d notificaciones nuevas en %d repositorios",
+		"release.assetsReady":     "Los archivos están disponibles",
+		"release.assetsLate":      "Los archivos aún no están disponibles",
+		"pull.opened":             "Abierta",
+		"pull.closed":             "Cerrada",
+		"pull.merged":             "Fusionada",
+		"reason.assign":           "te asignaron",
+		"reason.author":           "abriste el hilo",
+		"reason.comment":          "comentaste",
+		"reason.ci_activity":      "terminó un workflow que iniciaste",
+		"reason.invitation":       "te invitaron al repositorio",
+		"reason.manual":           "te suscribiste al hilo",
+		"reason.mention":          "te mencionaron",
+		"reason.review_requested": "solicitaron tu revisión",
+		"reason.security_alert":   "se generó una alerta de seguridad",
+		"reason.state_change":     "cambiaste el estado",
+		"reason.subscribed":       "estás observando el repositorio",
+		"reason.team_mention":     "mencionaron a tu equipo",
+		"notification.updated":    "actualizado %s",
+		"escalate.body":           "La notificación de %s en %s sigue sin leer",
+		"repogap.body":            "Se retuvieron %d notificaciones en %s para evitar la fatiga de alertas",
+		"unread.title":            "Bandeja de GitHub",
+		"unread.body":             "%d notificaciones sin leer",
+		"truncated.title":         "Resultados de GitHub truncados",
+		"truncated.body":          "Solo se leyeron las primeras %d páginas de %s. Ajusta tus filtros o aumenta maxPages.",
+		"ratelimit.title":         "Límite de peticiones de GitHub casi agotado",
+		"ratelimit.body":          "Quedan %s peticiones hasta %s, se consultará con menos frecuencia hasta entonces",
+		"alert.network":           "GitHub no está disponible",
+		"alert.auth":              "Token de GitHub rechazado, consultas en pausa hasta que se actualice la configuración",
+		"alert.rateLimit":         "Límite de peticiones de GitHub agotado, consultas en pausa hasta %s",
+		"alert.api":               "Error de la API de GitHub",
+		"outage.title":            "GitHub parece tener problemas, consultas en pausa",
+		"outage.body":             "GitHub sigue respondiendo con errores de servidor. Las consultas se reanudan automáticamente cuando se recupere. Consulta https://www.githubstatus.com",
+		"outage.recovered":        "GitHub se ha recuperado, consultas reanudadas",
+		"outage.recoveredBody":    "GitHub no estuvo disponible durante %s.",
+	},
+}
+
+func supportedLanguages() string {
+	langs := make([]string, 0, len(stringTables))
+	for lang := range stringTables {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return strings.Join(langs, ", ")
+}
+
+func (c *MyPlugin) lookup(key string) (string, bool) {
+	if s, ok := c.customStrings[key]; ok {
+		return s, true
+	}
+	if s, ok := stringTables[c.language][key]; ok {
+		return s, true
+	}
+	s, ok := stringTables["en"][key]
+	return s, ok
+}
+
+// translate returns the text for key in the configured language, formatted
+// with args. The custom string table overrides the bundled ones.
+func (c *MyPlugin) translate(key string, args ...interface{}) string {
+	s, ok := c.lookup(key)
+	if !ok {
+		s = key
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// typeName translates a notification type label. Types without a
+// translation, e.g. ones GitHub added recently, keep their GitHub name.
+func (c *MyPlugin) typeName(label string) string {
+	if s, ok := c.lookup("type." + label); ok {
+		return s
+	}
+	return label
+}
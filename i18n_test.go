@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslate(t *testing.T) {
+	p := &MyPlugin{language: "de", customStrings: map[string]string{"star.title": "⭐"}}
+	assert.Equal(t, "Neue Diskussion-Benachrichtigung in o/r", p.translate("notification.body", p.typeName("Discussion"), "o/r"))
+	assert.Equal(t, "⭐", p.translate("star.title"), "custom strings win")
+	assert.Equal(t, "CheckSuite", p.typeName("CheckSuite"), "unknown types keep their GitHub name")
+	assert.Equal(t, "no.such.key", p.translate("no.such.key"))
+
+	p = &MyPlugin{}
+	assert.Equal(t, "New Star", p.translate("star.title"), "English is the fallback")
+}
+
+func TestBundledTablesAreComplete(t *testing.T) {
+	for lang, table := range stringTables {
+		for key := range stringTables["en"] {
+			assert.Contains(t, table, key, "%s is missing %s", lang, key)
+		}
+	}
+}
+
+func TestLanguageConfig(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"language": "fr"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Nouvelle notification PR dans octocat/hello-world", msgs[0].Message)
+}
+
+func TestLanguageValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "language": "tlh"}))
+}
+
+func TestStatusMessagesAreTranslated(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Only one")+`]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"language": "de"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.checkUnreadCount()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "GitHub-Posteingang", msgs[0].Title)
+	assert.Equal(t, "1 ungelesene Benachrichtigungen", msgs[0].Message)
+}
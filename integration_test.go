@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHandler struct {
+	mu       sync.Mutex
+	messages []plugin.Message
+}
+
+func (h *recordingHandler) SendMessage(msg plugin.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.messages = append(h.messages, msg)
+	return nil
+}
+
+func (h *recordingHandler) Messages() []plugin.Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]plugin.Message(nil), h.messages...)
+}
+
+type fixtureServer struct {
+	*httptest.Server
+	t      *testing.T
+	mu     sync.Mutex
+	routes map[string]http.HandlerFunc
+}
+
+func newFixtureServer(t *testing.T) *fixtureServer {
+	s := newUnstartedFixtureServer(t)
+	s.Start()
+	return s
+}
+
+// newUnstartedFixtureServer lets a test configure the underlying server,
+// e.g. its ConnState hook, before calling Start.
+func newUnstartedFixtureServer(t *testing.T) *fixtureServer {
+	s := &fixtureServer{t: t, routes: map[string]http.HandlerFunc{}}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		h, ok := s.routes[r.URL.Path]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fixtureServer) handle(path string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = h
+}
+
+func (s *fixtureServer) serveFixture(path, fixture string) {
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(s.t, err)
+	s.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+func newTestPlugin(t *testing.T, srv *fixtureServer, conf map[string]interface{}) (*MyPlugin, *recordingHandler) {
+	p := NewGotifyPluginInstance(plugin.UserContext{ID: 1, Name: "test"}).(*MyPlugin)
+	p.client = srv.Client()
+
+	// Start from the defaults like Gotify does and overlay the test's settings.
+	var merged map[string]interface{}
+	b, err := json.Marshal(p.DefaultConfig())
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &merged))
+	merged["token"] = "test-token"
+	merged["apiBaseURL"] = srv.URL
+	for k, v := range conf {
+		merged[k] = v
+	}
+	require.NoError(t, p.ValidateAndSetConfig(merged))
+	rec := &recordingHandler{}
+	p.SetMessageHandler(rec)
+	return p, rec
+}
+
+func TestPollPipelineSendsNewNotificationsAndStars(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	srv.serveFixture("/user/repos", "user_repos.json")
+	srv.serveFixture("/repos/octocat/hello-world/stargazers", "stargazers_initial.json")
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, rec.Messages(), "initial state must not produce messages")
+
+	srv.serveFixture("/notifications", "notifications.json")
+	srv.serveFixture("/repos/octocat/hello-world/stargazers", "stargazers.json")
+	p.checkNotifications()
+	p.checkStars()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+
+	assert.Equal(t, "[PR] Fix the thing", msgs[0].Title)
+	assert.Equal(t, "New PR notification in octocat/hello-world", msgs[0].Message)
+	assert.Equal(t, 2, msgs[0].Priority)
+	assert.Equal(t, srv.URL+"/octocat/hello-world/pull/42",
+		msgs[0].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+
+	assert.Equal(t, "New Star", msgs[1].Title)
+	assert.Equal(t, "Repo octocat/hello-world received a star from bob", msgs[1].Message)
+
+	p.checkNotifications()
+	p.checkStars()
+	assert.Len(t, rec.Messages(), 2, "already seen items must not be re-sent")
+}
+
+func serveJSON(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// redactor replaces the configured secrets with *** in everything the
+// plugin logs, so neither the GitHub token nor the app token ends up in the
+// Gotify log, whatever an error message or debug line contains.
+type redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+	// minted is the current GitHub App installation token.
+	minted string
+}
+
+func (r *redactor) set(secrets ...string) {
+	var list []string
+	for _, secret := range secrets {
+		if secret != "" {
+			list = append(list, secret)
+		}
+	}
+	r.mu.Lock()
+	r.secrets = list
+	r.mu.Unlock()
+}
+
+// setMinted redacts a freshly minted installation token in place of the
+// previous one, so the list does not grow with every refresh.
+func (r *redactor) setMinted(token string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.minted = token
+	r.mu.Unlock()
+}
+
+func (r *redactor) redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	if r.minted != "" {
+		s = strings.ReplaceAll(s, r.minted, "***")
+	}
+	return s
+}
+
+// redactingWriter redacts every write before passing it on.
+type redactingWriter struct {
+	r   *redactor
+	out io.Writer
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.r.redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// newLogger returns a logger writing key=value lines to the output of the
+// standard logger, redacted by r. Debug lines, such as every request and why
+// a notification was skipped, are only written with debug set.
+func newLogger(debug bool, r *redactor) *slog.Logger {
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	w := redactingWriter{r: r, out: log.Writer()}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+func (c *MyPlugin) getLogger() *slog.Logger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.logger == nil {
+		c.logger = newLogger(false, c.redactor)
+	}
+	return c.logger
+}
+
+// infoLog writes an info line with the given key/value pairs.
+func (c *MyPlugin) infoLog(msg string, args ...any) {
+	c.getLogger().Info(msg, args...)
+}
+
+// debugLog writes a debug line with the given key/value pairs.
+func (c *MyPlugin) debugLog(msg string, args ...any) {
+	c.getLogger().Debug(msg, args...)
+}
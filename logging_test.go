@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of loggers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog redirects the standard logger, which the plugin's loggers write
+// to, for the rest of the test.
+func captureLog(t *testing.T) *syncBuffer {
+	buf := &syncBuffer{}
+	previous := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	return buf
+}
+
+func TestDebugLinesOnlyWithDebug(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		out := captureLog(t)
+		srv := newFixtureServer(t)
+		srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Hello")+`]`))
+		p, _ := newTestPlugin(t, srv, map[string]interface{}{"debug": debug})
+		require.NoError(t, p.Enable())
+		p.checkNotifications()
+		p.Disable()
+
+		if debug {
+			assert.Contains(t, out.String(), `level=DEBUG msg="github request" method=GET`)
+			assert.Contains(t, out.String(), "status=200")
+			assert.Contains(t, out.String(), `msg="skipping notification" id=1 reason=seen`)
+		} else {
+			assert.NotContains(t, out.String(), "level=DEBUG")
+		}
+		assert.NotContains(t, out.String(), "test-token")
+	}
+}
+
+func TestErrorsAreLoggedWithoutDebug(t *testing.T) {
+	out := captureLog(t)
+	p := &MyPlugin{clock: newFakeClock(), logger: newLogger(false, nil)}
+	p.recordError("fetching stargazers of octocat/hello-world", assert.AnError)
+	assert.Contains(t, out.String(), `level=ERROR msg="operation failed" op="fetching stargazers of octocat/hello-world"`)
+}
+
+func TestTokensAreRedactedFromLogs(t *testing.T) {
+	out := captureLog(t)
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"token": "ghp_secret", "apptoken": "AppSecret.12345", "debug": true})
+
+	p.infoLog("request failed", "url", "https://api.github.com/?access_token=ghp_secret")
+	p.recordError("sending with AppSecret.12345", errors.New("rejected ghp_secret"))
+	p.debugLog("auth", "header", "token ghp_secret")
+
+	assert.NotContains(t, out.String(), "ghp_secret")
+	assert.NotContains(t, out.String(), "AppSecret.12345")
+	assert.Contains(t, out.String(), `level=INFO msg="request failed" url="https://api.github.com/?access_token=***"`)
+	assert.Contains(t, out.String(), `err="rejected ***"`)
+	assert.Contains(t, out.String(), `header="token ***"`)
+}
+
+func TestGetDisplayRedactsToken(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"token": "ghp_secret"})
+	p.mu.Lock()
+	p.lastError = &errorStatus{Op: "polling GitHub", Message: "bad token ghp_secret"}
+	p.mu.Unlock()
+	assert.NotContains(t, p.GetDisplay(nil), "ghp_secret")
+	assert.Contains(t, p.GetDisplay(nil), "bad token ***")
+}
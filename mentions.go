@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+type searchIssue struct {
+	ID            int64  `json:"id"`
+	Number        int    `json:"number"`
+	Title         string `json:"title"`
+	HTMLURL       string `json:"html_url"`
+	RepositoryURL string `json:"repository_url"`
+	User          struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// searchReferences finds issues and pull requests in other repositories that
+// mention repo by its full name.
+func (c *MyPlugin) searchReferences(repo string) ([]searchIssue, error) {
+	query := url.Values{
+		"q":     {fmt.Sprintf(`"%s" -repo:%s`, repo, repo)},
+		"sort":  {"created"},
+		"order": {"desc"},
+	}
+	var result struct {
+		Items []searchIssue `json:"items"`
+	}
+	if err := c.getJSON(c.baseURL+"/search/issues?"+query.Encode(), "application/vnd.github.v3+json", &result); err != nil {
+		return nil, err
+	}
+	return result.Items, nil
+}
+
+// scanMentions records every referencing issue for the user's repositories
+// and, when notify is set, sends a message for the ones not seen before.
+// The search API is limited to 30 requests per minute, so users with many
+// repositories should pick a generous polling interval.
+func (c *MyPlugin) scanMentions(notify bool) {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for mention search", err)
+		return
+	}
+
+	for _, r := range repos {
+		repo := r.FullName
+		items, err := c.searchReferences(repo)
+		if err != nil {
+			c.recordError(fmt.Sprintf("searching references to %s", repo), err)
+			continue
+		}
+		for _, item := range items {
+			key := fmt.Sprint(item.ID)
+			if c.seenMentions[key] {
+				continue
+			}
+			c.seenMentions[key] = true
+			if !notify {
+				continue
+			}
+
+			source := item.RepositoryURL
+			if i := strings.Index(source, "/repos/"); i >= 0 {
+				source = source[i+len("/repos/"):]
+			}
+			msg := &plugin.Message{
+				Title:    fmt.Sprintf("%s referenced in %s", repo, source),
+				Message:  fmt.Sprintf("%s#%d: %s (by %s)", source, item.Number, item.Title, item.User.Login),
+				Priority: 2,
+				Extras: map[string]interface{}{
+					"client::notification": map[string]interface{}{
+						"click": map[string]interface{}{
+							"url": item.HTMLURL,
+						},
+					},
+				},
+			}
+			if err := c.msgHandler.SendMessage(*msg); err != nil {
+				c.recordError("sending mention notification", err)
+			} else {
+				c.infoLog("sent mention notification", "repo", repo, "source", source)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanMentionsNotifiesOnNewReferences(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.serveFixture("/user/repos", "user_repos.json")
+	var query string
+	srv.handle("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		serveJSON(`{"items":[{"id":1,"number":3,"title":"Old","html_url":"https://github.com/other/repo/issues/3",`+
+			`"repository_url":"https://api.github.com/repos/other/repo","user":{"login":"carol"}}]}`)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchMentions": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Equal(t, `"octocat/hello-world" -repo:octocat/hello-world`, query)
+
+	srv.handle("/search/issues", serveJSON(`{"items":[`+
+		`{"id":2,"number":7,"title":"Switch to hello-world","html_url":"https://github.com/other/repo/issues/7",`+
+		`"repository_url":"https://api.github.com/repos/other/repo","user":{"login":"dave"}},`+
+		`{"id":1,"number":3,"title":"Old","html_url":"https://github.com/other/repo/issues/3",`+
+		`"repository_url":"https://api.github.com/repos/other/repo","user":{"login":"carol"}}]}`))
+	p.scanMentions(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "octocat/hello-world referenced in other/repo", msgs[0].Title)
+	assert.Equal(t, "other/repo#7: Switch to hello-world (by dave)", msgs[0].Message)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+// noteServerError counts consecutive 5xx responses to the notifications poll.
+// Once outageThreshold is reached GitHub is considered down: the user gets a
+// single message and polling is reduced to the notifications request, which
+// doubles as the recovery probe.
+func (c *MyPlugin) noteServerError() {
+	c.mu.Lock()
+	c.serverErrors++
+	started := c.outageSince.IsZero() && c.serverErrors >= c.outageThreshold
+	if started {
+		c.outageSince = c.clock.Now()
+	}
+	c.mu.Unlock()
+
+	if started {
+		c.infoLog("GitHub keeps returning server errors, pausing polling until it recovers", "failures", c.outageThreshold)
+		c.sendOutageMessage(c.translate("outage.title"), c.translate("outage.body"), 4)
+	}
+}
+
+// endOutage resets the server error count after a successful poll and tells
+// the user when this ends an outage.
+func (c *MyPlugin) endOutage() {
+	c.mu.Lock()
+	since := c.outageSince
+	c.serverErrors = 0
+	c.outageSince = time.Time{}
+	c.mu.Unlock()
+
+	if !since.IsZero() {
+		c.infoLog("GitHub recovered, polling resumed")
+		c.sendOutageMessage(c.translate("outage.recovered"),
+			c.translate("outage.recoveredBody", formatAge(c.clock.Now().Sub(since).Round(time.Minute))), 2)
+	}
+}
+
+func (c *MyPlugin) inOutage() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.outageSince.IsZero()
+}
+
+func (c *MyPlugin) sendOutageMessage(title, message string, priority int) {
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  message,
+		Priority: priority,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": "https://www.githubstatus.com",
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending outage notification", err)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSustainedServerErrorsPausePolling(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unicorn", http.StatusServiceUnavailable)
+	})
+	starRequests := 0
+	srv.handle("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		starRequests++
+		serveJSON(`[]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"detectOutages": true, "outageThreshold": 3, "watchStars": true})
+	clk := newFakeClock()
+	p.clock = clk
+	p.seenNotifications = seenSet{"1": clk.Now()}
+	p.seenStars = seenSet{}
+
+	p.poll()
+	p.poll()
+	assert.Empty(t, rec.Messages(), "single server errors are retried quietly")
+	assert.Equal(t, 2, starRequests)
+
+	p.poll()
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "GitHub appears to be having issues, polling paused", msgs[0].Title)
+	assert.True(t, p.inOutage())
+	assert.Equal(t, 2, starRequests, "only the probe runs during an outage")
+
+	clk.Advance(25 * time.Minute)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p.poll()
+	msgs = rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "GitHub recovered, polling resumed", msgs[1].Title)
+	assert.Equal(t, "GitHub was unavailable for 25m.", msgs[1].Message)
+	assert.False(t, p.inOutage())
+	assert.Equal(t, 3, starRequests)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/gotify/plugin-api"
+)
+
+var (
+	linkNextRe     = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+	linkLastPageRe = regexp.MustCompile(`<([^>]+)>;\s*rel="last"`)
+)
+
+// fetchAllPages GETs endpoint and follows the rel="next" links of the Link
+// header, decoding every page into a []T. At most c.maxPages pages are read;
+// when the cap cuts the result short the user is warned once per endpoint.
+func fetchAllPages[T any](c *MyPlugin, endpoint, accept string) ([]T, error) {
+	return fetchRemainingPages[T](c, endpoint, endpoint, 0, accept)
+}
+
+// fetchRemainingPages continues a paginated fetch of endpoint at next after
+// fetched pages were already read by the caller.
+func fetchRemainingPages[T any](c *MyPlugin, endpoint, next string, fetched int, accept string) ([]T, error) {
+	all, _, err := fetchPages[T](c, endpoint, next, fetched, accept)
+	return all, err
+}
+
+// fetchPages is fetchRemainingPages that also reports whether maxPages cut
+// the result short.
+func fetchPages[T any](c *MyPlugin, endpoint, next string, fetched int, accept string) (all []T, truncated bool, err error) {
+	for page := fetched + 1; next != ""; page++ {
+		if page > c.maxPages {
+			c.warnTruncated(endpoint)
+			return all, true, nil
+		}
+		req, err := c.newGitHubRequest(c.requestContext(), "GET", next, accept, nil)
+		if err != nil {
+			return all, false, err
+		}
+		resp, err := c.do(req)
+		if err != nil {
+			return all, false, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return all, false, responseError(resp)
+		}
+		var items []T
+		err = json.NewDecoder(resp.Body).Decode(&items)
+		resp.Body.Close()
+		if err != nil {
+			return all, false, err
+		}
+		all = append(all, items...)
+
+		next = ""
+		if m := linkNextRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			next = m[1]
+		}
+	}
+	return all, false, nil
+}
+
+func (c *MyPlugin) warnTruncated(endpoint string) {
+	key := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		key = u.Path
+	}
+
+	c.mu.Lock()
+	if c.truncated == nil {
+		c.truncated = make(map[string]bool)
+	}
+	warned := c.truncated[key]
+	c.truncated[key] = true
+	c.mu.Unlock()
+
+	c.infoLog("stopped paginating", "endpoint", key, "pages", c.maxPages)
+	if warned {
+		return
+	}
+	msg := &plugin.Message{
+		Title:    c.translate("truncated.title"),
+		Message:  c.translate("truncated.body", c.maxPages, key),
+		Priority: 4,
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending truncation warning", err)
+	}
+}
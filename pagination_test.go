@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchAllPagesFollowsLinksUpToMaxPages(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/items", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%s0>; rel="next", <%s/items?page=99>; rel="last"`, srv.URL, page, srv.URL))
+		serveJSON(fmt.Sprintf(`[%q]`, page))(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"maxPages": 3})
+	items, err := fetchAllPages[string](p, srv.URL+"/items", "application/json")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "10", "100"}, items)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "GitHub results truncated", msgs[0].Title)
+
+	_, err = fetchAllPages[string](p, srv.URL+"/items", "application/json")
+	require.NoError(t, err)
+	assert.Len(t, rec.Messages(), 1, "the truncation warning is sent only once")
+}
+
+func TestMaxPagesValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "maxPages": 0}))
+}
+
+func notificationJSON(id, title string) string {
+	return fmt.Sprintf(`{"id":%q,"repository":{"full_name":"octocat/hello-world"},`+
+		`"subject":{"title":%q,"type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}`, id, title)
+}
+
+func TestNotificationsFollowPagination(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON("["+notificationJSON("2", "Second page")+"]")(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"page-1"`)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/notifications?page=2>; rel="next"`, srv.URL))
+		serveJSON("["+notificationJSON("1", "First page")+"]")(w, r)
+	})
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "[Issue] First page", msgs[0].Title)
+	assert.Equal(t, "[Issue] Second page", msgs[1].Title)
+	assert.Equal(t, `"page-1"`, p.notificationsETag)
+}
+
+func TestNotificationsKeepEarlierPagesOnError(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("ETag", `"page-1"`)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/notifications?page=2>; rel="next"`, srv.URL))
+		serveJSON("["+notificationJSON("1", "First page")+"]")(w, r)
+	})
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[Issue] First page", msgs[0].Title)
+	assert.Empty(t, p.notificationsETag, "a partial result must not be cached")
+}
+
+func TestStargazersFollowPagination(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	secondPage := `[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"bob"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON(secondPage)(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"first-page"`)
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/octocat/hello-world/stargazers?page=2>; rel="next"`, srv.URL))
+		serveJSON(`[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, p.stargazerETags, "paginated repos are not fetched conditionally")
+
+	secondPage = `[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"bob"}},` +
+		`{"starred_at":"2024-05-01T11:00:00Z","user":{"login":"carol"}}]`
+	p.checkStars()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Repo octocat/hello-world received a star from carol", msgs[0].Message)
+}
+
+func TestDuplicateThreadsInOneFetchNotifyOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON("["+notificationJSON("1", "Moved")+","+notificationJSON("3", "Third")+"]")(w, r)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/notifications?page=2>; rel="next"`, srv.URL))
+		serveJSON("["+notificationJSON("1", "Moved")+","+notificationJSON("2", "Second")+","+notificationJSON("2", "Second")+"]")(w, r)
+	})
+	notifications, _, err := p.fetchNotifications()
+	require.NoError(t, err)
+	assert.Len(t, notifications, 3)
+
+	p.notificationsETag = ""
+	p.checkNotifications()
+	var titles []string
+	for _, msg := range rec.Messages() {
+		titles = append(titles, msg.Title)
+	}
+	assert.Equal(t, []string{"[Issue] Moved", "[Issue] Second", "[Issue] Third"}, titles)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pause stops polling without disabling the plugin. All in-memory state is
+// kept so resuming picks up where polling left off.
+func (c *MyPlugin) pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pausedAt.IsZero() {
+		return false
+	}
+	c.pausedAt = c.clock.Now()
+	c.resumePending = false
+	return true
+}
+
+// resume restarts polling. The next poll catches up instead of notifying
+// about everything that arrived while paused, see catchUpAfterPause.
+func (c *MyPlugin) resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pausedAt.IsZero() {
+		return false
+	}
+	c.resumeFrom = c.pausedAt
+	c.pausedAt = time.Time{}
+	c.resumePending = true
+	return true
+}
+
+func (c *MyPlugin) getPausedAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pausedAt
+}
+
+// catchUpAfterPause runs on the first poll after a resume. With
+// replayOnEnable the notifications that changed while paused are replayed
+// like after a restart; otherwise they are only marked as seen.
+func (c *MyPlugin) catchUpAfterPause() bool {
+	c.mu.Lock()
+	pending, from := c.resumePending, c.resumeFrom
+	c.resumePending = false
+	c.mu.Unlock()
+	if !pending {
+		return false
+	}
+
+	var replay []GithubNotification
+	if c.replayOnEnable {
+		replay = c.fetchReplay(from)
+	}
+	c.fetchInitialState(true)
+	c.deliverReplay(replay, c.stopChannel)
+	return true
+}
+
+func (c *MyPlugin) handlePause(ctx *gin.Context) {
+	if c.pause() {
+		c.infoLog("polling paused via webhook")
+	}
+	ctx.JSON(http.StatusOK, gin.H{"paused": true})
+}
+
+func (c *MyPlugin) handleResume(ctx *gin.Context) {
+	if c.resume() {
+		c.infoLog("polling resumed via webhook")
+	}
+	ctx.JSON(http.StatusOK, gin.H{"paused": false})
+}
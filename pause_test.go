@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseAndResumeWebhooks(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	r := newWebhookRouter(p)
+	post := func(path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Webhook-Secret", "s3cret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, post("/pause"))
+	assert.True(t, p.isPaused())
+	assert.True(t, p.enabled)
+	assert.Contains(t, p.GetDisplay(nil), "Polling is paused")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	var status statusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Paused)
+	assert.NotNil(t, status.PausedAt)
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.poll()
+	assert.Empty(t, rec.Messages(), "no polling while paused")
+
+	require.Equal(t, http.StatusOK, post("/resume"))
+	assert.False(t, p.isPaused())
+	p.poll()
+	assert.Empty(t, rec.Messages(), "resuming marks the backlog as seen instead of flooding")
+	assert.True(t, p.seenNotifications.has("2"))
+	assert.True(t, p.seenNotifications.has("1"), "state from before the pause is kept")
+	assert.NotContains(t, p.GetDisplay(nil), "Polling is paused")
+}
+
+func TestPauseRequiresManagement(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	r := newWebhookRouter(p)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pause", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, p.isPaused())
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/gotify/plugin-api"
@@ -18,335 +22,1675 @@ type GithubNotification struct {
 		FullName string `json:"full_name"`
 	} `json:"repository"`
 	Subject struct {
-		Title string `json:"title"`
-		Type  string `json:"type"`
-		URL   string `json:"url"`
+		Title            string `json:"title"`
+		Type             string `json:"type"`
+		URL              string `json:"url"`
+		LatestCommentURL string `json:"latest_comment_url"`
 	} `json:"subject"`
+	Reason    string    `json:"reason"`
+	Unread    bool      `json:"unread"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+const (
+	// minPollInterval keeps polling within what GitHub tolerates; the
+	// notifications API itself asks for 60 seconds via X-Poll-Interval.
+	minPollInterval = 10
+	// maxPollInterval is one day, in seconds.
+	maxPollInterval = 24 * 60 * 60
+)
+
 type MyPlugin struct {
 	ctx               plugin.UserContext
 	enabled           bool
 	stopChannel       chan struct{}
 	githubToken       string
+	githubApp         *githubApp
+	tokenScheme       string
 	pollInterval      time.Duration
+	maxBackoff        time.Duration
+	requestTimeout    time.Duration
+	userAgent         string
+	proxyURL          *url.URL
+	intervalChanged   chan struct{}
 	lastCheckTime     time.Time
 	lastStarCheckTime time.Time
-	appID             uint
-	appToken          string
-	watchStars        bool
+	notificationsETag string
+	lastNotifications []GithubNotification
+	// notificationsSince limits the first poll after a restart to the
+	// threads updated since the last check.
+	notificationsSince time.Time
+	stargazerETags     map[string]string
+	appToken           string
+	gotifyURL          string
+	watchStars         bool
+	notifyUnstars      bool
+	starWorkers        int
+	starRepos          []string
+	markAsRead         bool
+	includeRead        bool
+	// unstarCandidates holds stars missing from one read of a paginated
+	// stargazer list, see checkUnstars.
+	unstarCandidates  map[string]bool
 	msgHandler        plugin.MessageHandler
-	seenNotifications map[string]bool
-	seenStars         map[string]bool
+	rawHandler        plugin.MessageHandler
+	label             string
+	accounts          []*MyPlugin
+	baseURL           string
+	client            *http.Client
+	clock             clock
+	seenNotifications seenSet
+	seenStars         seenSet
+	seenTTL           time.Duration
+	watchUnreadCount  bool
+	repoMinGap        time.Duration
+	repoLastSent      map[string]time.Time
+	repoSuppressed    map[string]heldBack
+
+	unknownTypePriority  int
+	suppressUnknownTypes bool
+
+	watchMentions bool
+	seenMentions  map[string]bool
+	watchWiki     bool
+	seenWikiEdits map[string]bool
+
+	showEngagement  bool
+	showDiffStat    bool
+	showPullState   bool
+	showRepoContext bool
+	showReasonTime  bool
+	repoInfoCache   map[string]*repoInfo
+	pullCache       map[string]*pullDetail
+	format          string
+	titleSource     string
+	titleTemplate   string
+	titleTmpl       *template.Template
+	messageTmpl     *template.Template
+	useMarkdown     bool
+	useEmoji        bool
+	digestMode      bool
+	digestThreshold int
+	language        string
+	location        *time.Location
+	customStrings   map[string]string
+
+	repoPriorities    map[string]int
+	typePriorities    map[string]int
+	showReasonMarkers bool
+	reasonMarkers     map[string]string
+
+	discussionComments bool
+	discussions        map[string]*discussionState
+
+	waitForReleaseAssets bool
+	minReleaseAssets     int
+	releaseAssetTimeout  time.Duration
+	pendingReleases      map[string]*pendingRelease
+
+	orgs            []string
+	repoAffiliation string
+
+	watchThreads        []threadRef
+	watchThreadPriority int
+	threadStates        map[string]*threadState
+	seenThreadComments  map[string]bool
+
+	watchCollaborators     bool
+	collaborators          map[string]map[string]string
+	collaboratorsForbidden map[string]bool
+
+	watchSponsors       bool
+	sponsors            map[string]sponsorRecord
+	sponsorsUnavailable bool
+
+	watchForcePushes     bool
+	branchHeads          map[string]string
+	branchHeadsForbidden map[string]bool
+
+	watchWorkflows        bool
+	seenWorkflowRuns      map[string]bool
+	workflowRunsForbidden map[string]bool
+
+	watchReleases     bool
+	releaseRepos      []string
+	releaseNotes      bool
+	seenReleases      map[string]bool
+	releasesForbidden map[string]bool
+
+	watchFollowers bool
+	seenFollowers  map[string]bool
+
+	watchForks     bool
+	seenForks      map[string]bool
+	forksForbidden map[string]bool
+
+	watchSecurityAlerts     bool
+	seenSecurityAlerts      map[string]bool
+	securityAlertsForbidden map[string]bool
+
+	trafficDigest     bool
+	trafficHour       int
+	lastTrafficDigest string
+	trafficForbidden  map[string]bool
+
+	vipActors   map[string]bool
+	vipPriority int
+
+	alertOnNetworkErrors bool
+	alertOnAPIErrors     bool
+	detectOutages        bool
+	rateLimitWarning     int
+
+	userCacheTTL      time.Duration
+	userLookupRetries int
+	userRetryDelay    time.Duration
+	outageThreshold   int
+
+	escalateUnread       bool
+	escalateSchedule     []time.Duration
+	escalatePriorityStep int
+	unreadThreads        map[string]*unreadThread
+
+	snoozeRenotify bool
+
+	// allowManagement, webhookSecret and githubWebhookSecret are read by
+	// the HTTP handlers, outside pollMu, so they are guarded by mu.
+	allowManagement     bool
+	webhookSecret       string
+	webhookBasePath     string
+	githubWebhookSecret string
+
+	maxPages int
+
+	inlineConfig      json.RawMessage
+	configFile        string
+	configFileModTime time.Time
+	watchConfigFile   bool
+
+	store          *stateStore
+	storageKey     string
+	replayOnEnable bool
+	replayWindow   time.Duration
+	replayMaxItems int
+	replayThrottle time.Duration
+	pendingReplay  []GithubNotification
+
+	sendLimiter sendLimiter
+
+	// pollMu serializes polls with Enable and configuration changes. It
+	// guards the seen state and every option a poll reads. GitHub is only
+	// called with pollMu held, so a poll can let go of it while it waits
+	// out the rate limit; polling, guarded by mu, is set while the goroutine
+	// holding pollMu for a poll is the only one making requests.
+	pollMu  sync.Mutex
+	polling bool
+
+	mu          sync.Mutex
+	unreadCount int
+	snoozed     map[string]time.Time
+	truncated   map[string]bool
+
+	lastSuccess   time.Time
+	lastError     *errorStatus
+	lastAlert     string
+	pausedForAuth bool
+	pausedUntil   time.Time
+	readAllAt     time.Time
+	ssoAlerted    map[string]bool
+	recentErrors  []*PluginError
+	serverErrors  int
+	outageSince   time.Time
+	user          authUser
+	userFetchedAt time.Time
+
+	configFilter   *notificationFilter
+	filterOverride *notificationFilter
+
+	pausedAt      time.Time
+	resumeFrom    time.Time
+	resumePending bool
+
+	// requestCtx is cancelled by Disable to abort requests in flight.
+	requestCtx    context.Context
+	cancelRequest context.CancelFunc
+
+	// rates holds the quota of each rate limit resource, so exhausting the
+	// search quota does not hold up the core REST calls.
+	rates           map[string]*rateWindow
+	rateWarnedReset time.Time
+
+	// pollFailures counts consecutive polls that failed with a transient
+	// network or server error; polling backs off while it is non-zero.
+	pollFailures int
+
+	// serverPollInterval is the minimum interval GitHub last asked for via
+	// X-Poll-Interval.
+	serverPollInterval time.Duration
+	// currentInterval is the interval the poller is waiting with, for
+	// GetDisplay.
+	currentInterval time.Duration
+	messagesSent    int
+
+	logger   *slog.Logger
+	redactor *redactor
+
+	quiet        quietWindow
+	quietEnabled bool
+	quietHandler *quietHandler
+	// vipHandler sends like msgHandler but skips quiet hours.
+	vipHandler plugin.MessageHandler
+}
+
+type Config struct {
+	Label            string `json:"label"`
+	Token            string `json:"token"`
+	APIBaseURL       string `json:"apiBaseURL"`
+	Interval         int    `json:"interval"`
+	MaxBackoff       int    `json:"maxBackoff"`
+	RequestTimeout   int    `json:"requestTimeout"`
+	UserAgentSuffix  string `json:"userAgentSuffix"`
+	TokenScheme      string `json:"tokenScheme"`
+	ProxyURL         string `json:"proxyURL"`
+	WatchStars       bool   `json:"watchStars"`
+	NotifyUnstars    bool   `json:"notifyUnstars"`
+	StarWorkers      int    `json:"starWorkers"`
+	StarRepos        string `json:"starRepos"`
+	WatchUnreadCount bool   `json:"watchUnreadCount"`
+	RepoMinGap       int    `json:"repoMinGap"`
+	MarkAsRead       bool   `json:"markAsRead"`
+
+	// GitHubAppID, GitHubInstallationID and GitHubAppPrivateKey (PEM)
+	// authenticate as a GitHub App installation instead of with token.
+	// Installation tokens cannot read a user's notifications, so those are
+	// skipped and the repo watchers, such as releases and workflows, watch
+	// the repositories the installation was granted.
+	GitHubAppID          int64  `json:"githubAppID"`
+	GitHubInstallationID int64  `json:"githubInstallationID"`
+	GitHubAppPrivateKey  string `json:"githubAppPrivateKey"`
+
+	// AppToken sends messages to the Gotify application with this token,
+	// through the REST API at GotifyURL, instead of to the plugin's own
+	// application. Leave it empty to use the plugin's application.
+	AppToken  string `json:"apptoken"`
+	GotifyURL string `json:"gotifyURL"`
+
+	// IncludeRead also lists notifications that were already read on
+	// GitHub. Read threads stay listed for weeks, so every poll reads more
+	// pages and uses more of the rate limit.
+	IncludeRead bool `json:"includeRead"`
+
+	UnknownTypePriority  int  `json:"unknownTypePriority"`
+	SuppressUnknownTypes bool `json:"suppressUnknownTypes"`
+
+	WatchMentions bool `json:"watchMentions"`
+	WatchWiki     bool `json:"watchWiki"`
+
+	ShowEngagement  bool `json:"showEngagement"`
+	ShowDiffStat    bool `json:"showDiffStat"`
+	ShowRepoContext bool `json:"showRepoContext"`
+	// ShowPullState marks pull request titles as opened, closed or merged
+	// and uses the PullRequest.closed and PullRequest.merged typePriorities.
+	ShowPullState bool `json:"showPullState"`
+	// ShowReasonAndTime adds why the notification was sent and when the
+	// thread was updated, e.g. "you were mentioned · updated 2024-05-01 12:00 CEST".
+	ShowReasonAndTime bool `json:"showReasonAndTime"`
+
+	DiscussionComments bool `json:"discussionComments"`
+
+	WaitForReleaseAssets bool `json:"waitForReleaseAssets"`
+	MinReleaseAssets     int  `json:"minReleaseAssets"`
+	ReleaseAssetTimeout  int  `json:"releaseAssetTimeout"`
+
+	Format        string `json:"format"`
+	TitleSource   string `json:"titleSource"`
+	TitleTemplate string `json:"titleTemplate"`
+	// MessageTemplate replaces the message body when set, using
+	// text/template syntax like "{{.Title}} in {{.Repo}}".
+	MessageTemplate string `json:"messageTemplate"`
+	UseMarkdown     bool   `json:"useMarkdown"`
+	// UseEmoji replaces the [Type] prefix of titles with an emoji, such as
+	// 🔀 for pull requests, and marks star messages with ⭐.
+	UseEmoji bool `json:"useEmoji"`
+
+	// DigestMode sends one summary message instead of individual ones when
+	// a poll finds more than DigestThreshold new notifications.
+	DigestMode      bool `json:"digestMode"`
+	DigestThreshold int  `json:"digestThreshold"`
+
+	// RepoPriorities maps an owner/repo name or pattern to the priority of
+	// its notifications, e.g. {"owner/critical":8,"owner/noisy":-1}. A
+	// priority of -1 mutes the repo: its notifications are marked seen
+	// without being sent.
+	RepoPriorities map[string]int `json:"repoPriorities"`
+	// TypePriorities maps a subject type such as Issue or CheckSuite, or
+	// "star", to the priority of its messages.
+	TypePriorities map[string]int `json:"typePriorities"`
+
+	IncludeRepos string `json:"includeRepos"`
+	ExcludeRepos string `json:"excludeRepos"`
+	Reasons      string `json:"reasons"`
+	Types        string `json:"types"`
+
+	ShowReasonMarkers bool              `json:"showReasonMarkers"`
+	ReasonMarkers     map[string]string `json:"reasonMarkers"`
+
+	Orgs string `json:"orgs"`
+	// RepoAffiliation selects which of the user's repos are listed, as a
+	// comma-separated subset of owner, collaborator and organization_member.
+	RepoAffiliation string `json:"repoAffiliation"`
+
+	WatchThreads        string `json:"watchThreads"`
+	WatchThreadPriority int    `json:"watchThreadPriority"`
+
+	WatchCollaborators bool `json:"watchCollaborators"`
+
+	WatchSponsors bool `json:"watchSponsors"`
+
+	WatchForcePushes bool `json:"watchForcePushes"`
+
+	WatchWorkflows bool `json:"watchWorkflows"`
+
+	WatchReleases bool   `json:"watchReleases"`
+	ReleaseRepos  string `json:"releaseRepos"`
+	// ReleaseNotes adds the start of the release body to the message.
+	ReleaseNotes bool `json:"releaseNotes"`
+
+	WatchFollowers bool `json:"watchFollowers"`
+
+	WatchForks bool `json:"watchForks"`
+
+	WatchSecurityAlerts bool `json:"watchSecurityAlerts"`
+
+	TrafficDigest bool `json:"trafficDigest"`
+	TrafficHour   int  `json:"trafficHour"`
+
+	VIPActors   string `json:"vipActors"`
+	VIPPriority int    `json:"vipPriority"`
+
+	// NotifyOnError sends a message when polling fails, once per kind of
+	// failure until polling works again. It is off by default;
+	// AlertOnAPIErrors and AlertOnNetworkErrors then choose which failures
+	// are reported.
+	NotifyOnError        bool `json:"notifyOnError"`
+	AlertOnNetworkErrors bool `json:"alertOnNetworkErrors"`
+	AlertOnAPIErrors     bool `json:"alertOnAPIErrors"`
+	// RateLimitWarning is the number of remaining REST API requests below
+	// which a warning is sent and polling slows down until the quota resets.
+	// The smaller search and GraphQL quotas do not count. 0 disables it.
+	RateLimitWarning int `json:"rateLimitWarning"`
+
+	Language      string            `json:"language"`
+	CustomStrings map[string]string `json:"customStrings"`
+	// Timezone is the IANA zone, such as Europe/Berlin, used for times in
+	// messages.
+	Timezone string `json:"timezone"`
+
+	UserCacheTTL      int `json:"userCacheTTL"`
+	UserLookupRetries int `json:"userLookupRetries"`
+
+	DetectOutages   bool `json:"detectOutages"`
+	OutageThreshold int  `json:"outageThreshold"`
+
+	EscalateUnread       bool   `json:"escalateUnread"`
+	EscalateAfter        string `json:"escalateAfter"`
+	EscalatePriorityStep int    `json:"escalatePriorityStep"`
+
+	SnoozeRenotify bool `json:"snoozeRenotify"`
+
+	AllowManagement bool   `json:"allowManagement"`
+	WebhookSecret   string `json:"webhookSecret"`
+	// GithubWebhookSecret verifies the signature of events GitHub posts to
+	// the github endpoint.
+	GithubWebhookSecret string `json:"githubWebhookSecret"`
+
+	MaxPages int `json:"maxPages"`
+
+	// SeenTTL is how many hours a notification thread or star is
+	// remembered after GitHub stopped listing it.
+	SeenTTL int `json:"seenTTL"`
+
+	// QuietStart and QuietEnd, as HH:MM in timezone, bound the daily quiet
+	// hours, which may span midnight. QuietMode "batch" holds messages until
+	// quiet hours end, "lower" sends them at priority 0. Notifications from
+	// VIP actors are sent right away.
+	QuietStart string `json:"quietStart"`
+	QuietEnd   string `json:"quietEnd"`
+	QuietMode  string `json:"quietMode"`
+
+	// ConfigFile names a JSON file in data/github-plugin, below Gotify's
+	// working directory, whose keys override the ones above. With
+	// WatchConfigFile it is reloaded when it changes, keeping the accounts
+	// and their connection state.
+	ConfigFile      string `json:"configFile"`
+	WatchConfigFile bool   `json:"watchConfigFile"`
+
+	ReplayOnEnable bool `json:"replayOnEnable"`
+	ReplayWindow   int  `json:"replayWindow"`
+	ReplayMaxItems int  `json:"replayMaxItems"`
+
+	MaxConcurrentSends int `json:"maxConcurrentSends"`
+
+	// Debug logs every request and why notifications were skipped.
+	Debug bool `json:"debug"`
+
+	Accounts []map[string]interface{} `json:"accounts"`
+
+	Description string `json:"description"`
 }
 
 func (c *MyPlugin) DefaultConfig() any {
-	return &struct {
-		Token       string `json:"token"`
-		Interval    int    `json:"interval"`
-		AppToken    string `json:"apptoken"`
-		WatchStars  bool   `json:"watchStars"`
-		Description string `json:"description"`
-	}{
-		Token:       "",
-		Interval:    60,
-		AppToken:    "",
-		WatchStars:  false,
+	return &Config{
+		Label:            "",
+		Token:            "",
+		APIBaseURL:       "https://api.github.com",
+		Interval:         60,
+		MaxBackoff:       900,
+		RequestTimeout:   30,
+		UserAgentSuffix:  "",
+		TokenScheme:      tokenSchemeAuto,
+		ProxyURL:         "",
+		WatchStars:       false,
+		NotifyUnstars:    false,
+		StarWorkers:      4,
+		StarRepos:        "",
+		WatchUnreadCount: false,
+		RepoMinGap:       0,
+		MarkAsRead:       false,
+
+		GitHubAppID:          0,
+		GitHubInstallationID: 0,
+		GitHubAppPrivateKey:  "",
+
+		AppToken:  "",
+		GotifyURL: "http://localhost",
+
+		IncludeRead: false,
+
+		UnknownTypePriority:  2,
+		SuppressUnknownTypes: false,
+
+		WatchMentions: false,
+		WatchWiki:     false,
+
+		ShowEngagement:    false,
+		ShowDiffStat:      false,
+		ShowRepoContext:   false,
+		ShowPullState:     false,
+		ShowReasonAndTime: false,
+
+		DiscussionComments: false,
+
+		WaitForReleaseAssets: false,
+		MinReleaseAssets:     1,
+		ReleaseAssetTimeout:  30,
+
+		Format:          formatDefault,
+		TitleSource:     titleSubject,
+		TitleTemplate:   "{repo} #{number}",
+		MessageTemplate: "",
+		UseMarkdown:     false,
+		UseEmoji:        false,
+
+		DigestMode:      false,
+		DigestThreshold: 5,
+
+		RepoPriorities: nil,
+		TypePriorities: defaultTypePriorities(),
+
+		IncludeRepos: "",
+		ExcludeRepos: "",
+		Reasons:      "",
+		Types:        "",
+
+		ShowReasonMarkers: false,
+		ReasonMarkers:     defaultReasonMarkers(),
+
+		Orgs:            "",
+		RepoAffiliation: "owner,collaborator,organization_member",
+
+		WatchThreads:        "",
+		WatchThreadPriority: 6,
+
+		WatchCollaborators: false,
+
+		WatchSponsors: false,
+
+		WatchForcePushes: false,
+
+		WatchWorkflows: false,
+
+		WatchReleases: false,
+		ReleaseRepos:  "",
+		ReleaseNotes:  false,
+
+		WatchFollowers: false,
+
+		WatchForks: false,
+
+		WatchSecurityAlerts: false,
+
+		TrafficDigest: false,
+		TrafficHour:   9,
+
+		VIPActors:   "",
+		VIPPriority: 8,
+
+		NotifyOnError:        false,
+		AlertOnNetworkErrors: false,
+		AlertOnAPIErrors:     true,
+		RateLimitWarning:     100,
+
+		Language:      "en",
+		CustomStrings: nil,
+		Timezone:      "UTC",
+
+		UserCacheTTL:      720,
+		UserLookupRetries: 2,
+
+		DetectOutages:   false,
+		OutageThreshold: 3,
+
+		EscalateUnread:       false,
+		EscalateAfter:        "60,240,1440",
+		EscalatePriorityStep: 2,
+
+		SnoozeRenotify: false,
+
+		AllowManagement:     false,
+		WebhookSecret:       "",
+		GithubWebhookSecret: "",
+
+		MaxPages: 10,
+
+		SeenTTL: 720,
+
+		QuietStart: "",
+		QuietEnd:   "",
+		QuietMode:  quietBatch,
+
+		ConfigFile:      "",
+		WatchConfigFile: false,
+
+		ReplayOnEnable: false,
+		ReplayWindow:   24,
+		ReplayMaxItems: 20,
+
+		MaxConcurrentSends: 1,
+
+		Debug: false,
+
+		Accounts: nil,
+
 		Description: "Enter GitHub token, polling interval (seconds), Gotify application token, and enable star notifications",
 	}
 }
 
 func (c *MyPlugin) ValidateAndSetConfig(cfg interface{}) error {
+	return c.setConfig(cfg, false)
+}
+
+// setConfig validates and applies cfg. A reload of the config file keeps
+// the accounts, reconfiguring them in place, and keeps the connection state
+// while the credentials stay the same.
+func (c *MyPlugin) setConfig(cfg interface{}, reload bool) error {
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
 	b, err := json.Marshal(cfg)
 	if err != nil {
 		return err
 	}
-	var conf struct {
-		Token       string `json:"token"`
-		Interval    int    `json:"interval"`
-		AppToken    string `json:"apptoken"`
-		WatchStars  bool   `json:"watchStars"`
-		Description string `json:"description"`
-	}
+	conf := *c.DefaultConfig().(*Config)
 	if err = json.Unmarshal(b, &conf); err != nil {
 		return err
 	}
-	if conf.Token == "" {
+	var configFileModTime time.Time
+	if conf.ConfigFile != "" {
+		if configFileModTime, err = mergeConfigFile(&conf); err != nil {
+			return err
+		}
+	}
+
+	// Validate everything before touching the plugin, so a rejected
+	// configuration leaves the running one as it was.
+	app, err := parseGitHubApp(conf)
+	if err != nil {
+		return err
+	}
+	switch {
+	case conf.Token == "" && app == nil:
 		return fmt.Errorf("GitHub token is required")
+	case conf.Token != "" && app != nil:
+		return fmt.Errorf("set either a GitHub token or a GitHub App, not both")
+	}
+	switch conf.TokenScheme {
+	case tokenSchemeAuto, tokenSchemeToken, tokenSchemeBearer:
+	default:
+		return fmt.Errorf("unknown tokenScheme %q, expected %q, %q or %q", conf.TokenScheme, tokenSchemeAuto, tokenSchemeToken, tokenSchemeBearer)
+	}
+	baseURL, err := parseAPIBaseURL(conf.APIBaseURL)
+	if err != nil {
+		return err
+	}
+	if conf.Interval < minPollInterval || conf.Interval > maxPollInterval {
+		return fmt.Errorf("interval must be between %d and %d seconds, got %d", minPollInterval, maxPollInterval, conf.Interval)
+	}
+	if conf.MaxBackoff < minPollInterval {
+		return fmt.Errorf("maxBackoff must be at least %d seconds, got %d", minPollInterval, conf.MaxBackoff)
+	}
+	if conf.RequestTimeout < 1 {
+		return fmt.Errorf("requestTimeout must be at least 1 second")
+	}
+	if strings.ContainsAny(conf.UserAgentSuffix, "\r\n") {
+		return fmt.Errorf("userAgentSuffix must be a single line")
+	}
+	proxyURL, err := parseProxyURL(conf.ProxyURL)
+	if err != nil {
+		return err
+	}
+	if err := validateAppToken(conf.AppToken); err != nil {
+		return err
+	}
+	var gotifyURL string
+	if conf.AppToken != "" {
+		if gotifyURL, err = parseGotifyURL(conf.GotifyURL); err != nil {
+			return err
+		}
+	}
+	if conf.StarWorkers < 1 {
+		return fmt.Errorf("starWorkers must be at least 1")
+	}
+	starRepos, err := parseRepoList("starRepos", conf.StarRepos)
+	if err != nil {
+		return err
+	}
+	if conf.RepoMinGap < 0 {
+		return fmt.Errorf("repoMinGap must not be negative")
+	}
+	if conf.UnknownTypePriority < 0 || conf.UnknownTypePriority > 10 {
+		return fmt.Errorf("unknownTypePriority must be between 0 and 10")
+	}
+	if conf.MinReleaseAssets < 1 {
+		return fmt.Errorf("minReleaseAssets must be at least 1")
+	}
+	if conf.ReleaseAssetTimeout < 1 {
+		return fmt.Errorf("releaseAssetTimeout must be at least 1 minute")
+	}
+	switch conf.Format {
+	case formatDefault, formatCompact:
+	default:
+		return fmt.Errorf("unknown format %q, expected %q or %q", conf.Format, formatDefault, formatCompact)
+	}
+	switch conf.TitleSource {
+	case titleSubject, titleRepo:
+	case titleCustom:
+		if strings.TrimSpace(conf.TitleTemplate) == "" {
+			return fmt.Errorf("titleTemplate is required when titleSource is %q", titleCustom)
+		}
+	default:
+		return fmt.Errorf("unknown titleSource %q, expected %q, %q or %q", conf.TitleSource, titleSubject, titleRepo, titleCustom)
+	}
+	if _, ok := stringTables[conf.Language]; !ok {
+		return fmt.Errorf("unknown language %q, expected one of %s", conf.Language, supportedLanguages())
+	}
+	location, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q: %w", conf.Timezone, err)
+	}
+	quiet, quietEnabled, err := parseQuietHours(conf, location)
+	if err != nil {
+		return err
+	}
+	var titleTmpl, messageTmpl *template.Template
+	if isGoTemplate(conf.TitleTemplate) {
+		if titleTmpl, err = parseTemplate("titleTemplate", conf.TitleTemplate); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(conf.MessageTemplate) != "" {
+		if messageTmpl, err = parseTemplate("messageTemplate", conf.MessageTemplate); err != nil {
+			return err
+		}
+	}
+	if conf.DigestThreshold < 1 {
+		return fmt.Errorf("digestThreshold must be at least 1")
+	}
+	for pattern, priority := range conf.RepoPriorities {
+		if err := validateRepoPattern(pattern); err != nil {
+			return fmt.Errorf("repoPriorities: %w", err)
+		}
+		if priority != repoMuted && (priority < 0 || priority > 10) {
+			return fmt.Errorf("repoPriorities: priority of %q must be between 0 and 10, or -1 to mute", pattern)
+		}
+	}
+	for typ, priority := range conf.TypePriorities {
+		if priority < 0 || priority > 10 {
+			return fmt.Errorf("typePriorities: priority of %q must be between 0 and 10", typ)
+		}
+	}
+	filter := &notificationFilter{
+		IncludeRepos: splitList(conf.IncludeRepos),
+		ExcludeRepos: splitList(conf.ExcludeRepos),
+		Reasons:      splitList(conf.Reasons),
+		Types:        splitList(conf.Types),
+	}
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	affiliation := splitList(conf.RepoAffiliation)
+	if len(affiliation) == 0 {
+		return fmt.Errorf("repoAffiliation must list at least one of owner, collaborator, organization_member")
+	}
+	for _, a := range affiliation {
+		if a != "owner" && a != "collaborator" && a != "organization_member" {
+			return fmt.Errorf("unknown repoAffiliation %q, expected owner, collaborator or organization_member", a)
+		}
+	}
+	var threads []threadRef
+	for _, item := range splitList(conf.WatchThreads) {
+		ref, err := parseThreadURL(item)
+		if err != nil {
+			return err
+		}
+		threads = append(threads, ref)
+	}
+	if conf.WatchThreadPriority < 0 || conf.WatchThreadPriority > 10 {
+		return fmt.Errorf("watchThreadPriority must be between 0 and 10")
+	}
+	if conf.TrafficHour < 0 || conf.TrafficHour > 23 {
+		return fmt.Errorf("trafficHour must be between 0 and 23")
+	}
+	releaseRepos, err := parseRepoList("releaseRepos", conf.ReleaseRepos)
+	if err != nil {
+		return err
+	}
+	if conf.VIPPriority < 0 || conf.VIPPriority > 10 {
+		return fmt.Errorf("vipPriority must be between 0 and 10")
+	}
+	if conf.RateLimitWarning < 0 {
+		return fmt.Errorf("rateLimitWarning must not be negative")
+	}
+	if conf.OutageThreshold < 1 {
+		return fmt.Errorf("outageThreshold must be at least 1")
+	}
+	if conf.UserCacheTTL < 1 {
+		return fmt.Errorf("userCacheTTL must be at least 1 minute")
+	}
+	if conf.UserLookupRetries < 0 {
+		return fmt.Errorf("userLookupRetries must not be negative")
+	}
+	escalateSchedule, err := parseEscalateAfter(conf.EscalateAfter)
+	if err != nil {
+		return err
+	}
+	if conf.EscalatePriorityStep < 0 {
+		return fmt.Errorf("escalatePriorityStep must not be negative")
+	}
+	if conf.AllowManagement && conf.WebhookSecret == "" {
+		return fmt.Errorf("webhookSecret is required when allowManagement is enabled")
+	}
+	if conf.MaxPages < 1 {
+		return fmt.Errorf("maxPages must be at least 1")
+	}
+	if conf.SeenTTL < 1 {
+		return fmt.Errorf("seenTTL must be at least 1 hour")
+	}
+	if conf.ReplayWindow < 1 {
+		return fmt.Errorf("replayWindow must be at least 1 hour")
+	}
+	if conf.ReplayMaxItems < 1 {
+		return fmt.Errorf("replayMaxItems must be at least 1")
+	}
+	if conf.MaxConcurrentSends < 1 {
+		return fmt.Errorf("maxConcurrentSends must be at least 1")
+	}
+	accounts, err := c.buildAccounts(conf)
+	if err != nil {
+		return err
+	}
+	keepConnection := reload && conf.Token == c.githubToken && app.equal(c.githubApp)
+	if keepConnection {
+		app = c.githubApp
+	}
+	if reload {
+		if accounts, err = c.reuseAccounts(accounts); err != nil {
+			return err
+		}
 	}
+
 	c.githubToken = conf.Token
-	c.pollInterval = time.Duration(conf.Interval) * time.Second
+	c.githubApp = app
+	c.tokenScheme = conf.TokenScheme
+	c.baseURL = baseURL
+	c.maxBackoff = time.Duration(conf.MaxBackoff) * time.Second
+	c.requestTimeout = time.Duration(conf.RequestTimeout) * time.Second
+	c.userAgent = userAgent(conf.UserAgentSuffix)
+	c.proxyURL = proxyURL
 	c.appToken = conf.AppToken
+	c.gotifyURL = gotifyURL
+	if c.redactor == nil {
+		c.redactor = &redactor{}
+	}
+	c.redactor.set(conf.Token, conf.AppToken, conf.GitHubAppPrivateKey)
 	c.watchStars = conf.WatchStars
+	c.notifyUnstars = conf.NotifyUnstars
+	c.markAsRead = conf.MarkAsRead
+	c.includeRead = conf.IncludeRead
+	c.starWorkers = conf.StarWorkers
+	c.starRepos = starRepos
+	c.watchUnreadCount = conf.WatchUnreadCount
+	c.repoMinGap = time.Duration(conf.RepoMinGap) * time.Minute
+	c.unknownTypePriority = conf.UnknownTypePriority
+	c.suppressUnknownTypes = conf.SuppressUnknownTypes
+	c.watchMentions = conf.WatchMentions
+	c.watchWiki = conf.WatchWiki
+	c.showEngagement = conf.ShowEngagement
+	c.showDiffStat = conf.ShowDiffStat
+	c.showPullState = conf.ShowPullState
+	c.showRepoContext = conf.ShowRepoContext
+	c.showReasonTime = conf.ShowReasonAndTime
+	c.discussionComments = conf.DiscussionComments
+	c.waitForReleaseAssets = conf.WaitForReleaseAssets
+	c.minReleaseAssets = conf.MinReleaseAssets
+	c.releaseAssetTimeout = time.Duration(conf.ReleaseAssetTimeout) * time.Minute
+	c.format = conf.Format
+	c.language = conf.Language
+	c.customStrings = conf.CustomStrings
+	c.location = location
+	c.quiet, c.quietEnabled = quiet, quietEnabled
+	c.titleSource = conf.TitleSource
+	c.titleTemplate = conf.TitleTemplate
+	c.titleTmpl = titleTmpl
+	c.messageTmpl = messageTmpl
+	c.useMarkdown = conf.UseMarkdown
+	c.useEmoji = conf.UseEmoji
+	c.digestMode = conf.DigestMode
+	c.digestThreshold = conf.DigestThreshold
+	c.repoPriorities = conf.RepoPriorities
+	c.typePriorities = conf.TypePriorities
+	c.showReasonMarkers = conf.ShowReasonMarkers
+	c.reasonMarkers = conf.ReasonMarkers
+	c.orgs = splitList(conf.Orgs)
+	c.repoAffiliation = strings.Join(affiliation, ",")
+	c.watchThreads = threads
+	c.watchThreadPriority = conf.WatchThreadPriority
+	c.watchCollaborators = conf.WatchCollaborators
+	c.watchSponsors = conf.WatchSponsors
+	c.watchForcePushes = conf.WatchForcePushes
+	c.watchWorkflows = conf.WatchWorkflows
+	c.watchReleases = conf.WatchReleases
+	c.releaseRepos = releaseRepos
+	c.releaseNotes = conf.ReleaseNotes
+	c.watchFollowers = conf.WatchFollowers
+	c.watchForks = conf.WatchForks
+	c.watchSecurityAlerts = conf.WatchSecurityAlerts
+	c.trafficDigest = conf.TrafficDigest
+	c.trafficHour = conf.TrafficHour
+	c.vipActors = make(map[string]bool)
+	for _, login := range splitList(conf.VIPActors) {
+		c.vipActors[strings.ToLower(login)] = true
+	}
+	c.vipPriority = conf.VIPPriority
+	c.alertOnNetworkErrors = conf.NotifyOnError && conf.AlertOnNetworkErrors
+	c.alertOnAPIErrors = conf.NotifyOnError && conf.AlertOnAPIErrors
+	c.rateLimitWarning = conf.RateLimitWarning
+	c.userCacheTTL = time.Duration(conf.UserCacheTTL) * time.Minute
+	c.userLookupRetries = conf.UserLookupRetries
+	c.detectOutages = conf.DetectOutages
+	c.outageThreshold = conf.OutageThreshold
+	c.escalateUnread = conf.EscalateUnread
+	c.escalateSchedule = escalateSchedule
+	c.escalatePriorityStep = conf.EscalatePriorityStep
+	c.snoozeRenotify = conf.SnoozeRenotify
+	c.maxPages = conf.MaxPages
+	c.seenTTL = time.Duration(conf.SeenTTL) * time.Hour
+	c.inlineConfig = b
+	c.configFile = conf.ConfigFile
+	c.configFileModTime = configFileModTime
+	c.watchConfigFile = conf.WatchConfigFile
+	c.replayOnEnable = conf.ReplayOnEnable
+	c.replayWindow = time.Duration(conf.ReplayWindow) * time.Hour
+	c.replayMaxItems = conf.ReplayMaxItems
+
+	c.mu.Lock()
+	if !keepConnection {
+		c.pausedForAuth = false
+		c.ssoAlerted = nil
+		c.user = authUser{}
+		c.userFetchedAt = time.Time{}
+	}
+	c.configFilter = filter
+	c.logger = newLogger(conf.Debug, c.redactor)
+	c.allowManagement = conf.AllowManagement
+	c.webhookSecret = conf.WebhookSecret
+	c.githubWebhookSecret = conf.GithubWebhookSecret
+	c.mu.Unlock()
+
+	if cap(c.sendLimiter) != conf.MaxConcurrentSends {
+		c.sendLimiter = newSendLimiter(conf.MaxConcurrentSends)
+	}
+	c.replaceAccounts(accounts)
+	c.label = conf.Label
+	c.applyMessageHandler()
+
+	if interval := time.Duration(conf.Interval) * time.Second; interval != c.pollInterval {
+		c.pollInterval = interval
+		// Wake the poller so it picks up the new interval right away.
+		select {
+		case c.intervalChanged <- struct{}{}:
+		default:
+		}
+	}
 	return nil
 }
 
 func (c *MyPlugin) Enable() error {
-	if c.appToken != "" {
-	} else {
-		c.appID = c.ctx.ID
-	}
+	c.pollMu.Lock()
+	c.mu.Lock()
 	c.enabled = true
-	c.lastCheckTime = time.Now()
+	c.requestCtx, c.cancelRequest = context.WithCancel(context.Background())
+	c.mu.Unlock()
+	// A hung connection must not stall the poller.
+	c.client.Timeout = c.requestTimeout
+	if t, ok := c.client.Transport.(*http.Transport); ok {
+		t.Proxy = c.proxy()
+	}
+	previous := c.loadState()
+	c.lastCheckTime = c.clock.Now()
 	if c.watchStars {
-		c.lastStarCheckTime = time.Now()
+		c.lastStarCheckTime = c.clock.Now()
 	}
 
-	c.seenNotifications = make(map[string]bool)
-	c.seenStars = make(map[string]bool)
+	c.seenNotifications = make(seenSet)
+	c.seenStars = make(seenSet)
+	c.unstarCandidates = make(map[string]bool)
+	c.stargazerETags = make(map[string]string)
+	c.repoLastSent = make(map[string]time.Time)
+	c.repoSuppressed = make(map[string]heldBack)
+	c.seenMentions = make(map[string]bool)
+	c.seenWikiEdits = make(map[string]bool)
+	c.unreadThreads = make(map[string]*unreadThread)
+	c.pendingReleases = make(map[string]*pendingRelease)
+	c.discussions = make(map[string]*discussionState)
+	c.repoInfoCache = make(map[string]*repoInfo)
+	c.pullCache = make(map[string]*pullDetail)
+	c.threadStates = make(map[string]*threadState)
+	c.seenThreadComments = make(map[string]bool)
+	c.collaborators = previous.Collaborators
+	if c.collaborators == nil {
+		c.collaborators = make(map[string]map[string]string)
+	}
+	c.collaboratorsForbidden = make(map[string]bool)
+	c.sponsors = previous.Sponsors
+	c.sponsorsUnavailable = false
+	c.lastTrafficDigest = previous.LastTrafficDigest
+	c.trafficForbidden = make(map[string]bool)
+	c.branchHeads = previous.BranchHeads
+	if c.branchHeads == nil {
+		c.branchHeads = make(map[string]string)
+	}
+	c.branchHeadsForbidden = make(map[string]bool)
+	c.seenWorkflowRuns = make(map[string]bool)
+	c.workflowRunsForbidden = make(map[string]bool)
+	c.seenReleases = make(map[string]bool)
+	c.releasesForbidden = make(map[string]bool)
+	c.seenFollowers = make(map[string]bool)
+	c.seenForks = make(map[string]bool)
+	c.forksForbidden = make(map[string]bool)
+	c.seenSecurityAlerts = make(map[string]bool)
+	c.securityAlertsForbidden = make(map[string]bool)
+	c.mu.Lock()
+	c.filterOverride = previous.Filter
+	c.mu.Unlock()
+	c.setUnreadCount(-1)
 
-	c.fetchInitialState()
+	c.notificationsSince = time.Time{}
+	if previous.SeenNotifications != nil {
+		c.notificationsSince = previous.LastCheckTime
+	}
+	c.fetchInitialState(c.notificationsSince.IsZero())
+	c.restoreSeen(previous)
+	if c.replayOnEnable && !previous.LastCheckTime.IsZero() {
+		c.pendingReplay = c.fetchReplay(previous.LastCheckTime)
+	}
+	c.restoreQuietQueue(previous.QuietQueue)
+	c.saveState()
 
 	c.stopChannel = make(chan struct{})
-	go c.startPolling()
+	replay := c.pendingReplay
+	c.pendingReplay = nil
+	go c.startPolling(replay, c.stopChannel)
+	c.pollMu.Unlock()
+
+	for _, account := range c.accounts {
+		if err := account.Enable(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *MyPlugin) fetchInitialState() {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://api.github.com/notifications", nil)
-	if err != nil {
-		return
-	}
-	req.Header.Add("Authorization", "token "+c.githubToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return
-	}
-	defer resp.Body.Close()
+// fetchInitialState records what is already on GitHub as seen. The
+// notifications are only read without saved state; otherwise the seen
+// threads of the last run are restored afterwards and the first poll reads
+// what changed since.
+func (c *MyPlugin) fetchInitialState(notifications bool) {
+	c.notificationsETag = ""
+	c.lastNotifications = nil
+	if notifications && c.readsNotifications() {
+		list, _, err := c.fetchNotifications()
+		if err != nil {
+			c.handlePollError(err)
+			return
+		}
+		c.lastNotifications = list
 
-	var notifications []GithubNotification
-	if err := json.NewDecoder(resp.Body).Decode(&notifications); err != nil {
-		return
+		now := c.clock.Now()
+		for _, notification := range list {
+			c.seenNotifications.mark(notification.ID, now)
+		}
 	}
 
-	for _, notification := range notifications {
-		c.seenNotifications[notification.ID] = true
+	if user, err := c.currentUser(); err != nil {
+		c.recordError("fetching the authenticated user", err)
+	} else {
+		c.infoLog("polling GitHub notifications", "login", user.Login)
 	}
 
 	if c.watchStars {
 		c.fetchInitialStars()
 	}
+	if c.watchMentions {
+		c.scanMentions(false)
+	}
+	if c.watchWiki {
+		c.scanWikiEdits(false)
+	}
+	if len(c.watchThreads) > 0 {
+		c.scanWatchedThreads(false)
+	}
+	if c.watchCollaborators {
+		c.scanCollaborators()
+	}
+	if c.watchSponsors {
+		c.checkSponsors()
+	}
+	if c.watchForcePushes {
+		c.scanForcePushes()
+	}
+	if c.watchWorkflows {
+		c.scanWorkflowRuns(false)
+	}
+	if c.watchReleases {
+		c.scanReleases(false)
+	}
+	if c.watchFollowers {
+		c.scanFollowers(false)
+	}
+	if c.watchForks {
+		c.scanForks(false)
+	}
+	if c.watchSecurityAlerts {
+		c.scanSecurityAlerts(false)
+	}
 }
 
-func (c *MyPlugin) fetchInitialStars() {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://api.github.com/user/repos", nil)
-	if err != nil {
-		return
+// fetchStargazers reads every page of repo's stargazers. Repos whose
+// stargazers fit on one page are requested conditionally. Larger repos are
+// always read in full, because new stars are appended to the last page and
+// leave the ETag of the first one untouched. Several repos are fetched at
+// once, see fetchStargazersOf.
+func (c *MyPlugin) fetchStargazers(repo string) (stargazerList, error) {
+	const accept = "application/vnd.github.v3.star+json"
+	endpoint := fmt.Sprintf("%s/repos/%s/stargazers?per_page=100", c.baseURL, repo)
+	etag := c.stargazerETags[repo]
+	var list stargazerList
+	changed, next, err := c.getJSONIfChanged(endpoint, accept, &etag, &list.stars)
+	list.changed = changed
+	if err != nil || !changed {
+		return list, err
 	}
-	req.Header.Add("Authorization", "token "+c.githubToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-	resp, err := client.Do(req)
-	if err != nil {
-		return
+	if next == "" {
+		list.etag = etag
+		return list, nil
 	}
-	defer resp.Body.Close()
+	list.paginated = true
+	rest, truncated, err := fetchPages[stargazer](c, endpoint, next, 1, accept)
+	list.stars = append(list.stars, rest...)
+	list.truncated = truncated
+	return list, err
+}
 
-	var repos []struct {
-		FullName string `json:"full_name"`
+// stargazersFailed records a failed stargazer fetch. It returns true when
+// the token was rejected or the rate limit is exhausted, in which case
+// polling is paused, or when Disable aborted the request; the remaining repos
+// should not be fetched then.
+func (c *MyPlugin) stargazersFailed(repo string, err error) bool {
+	if stopsStarFetches(err) {
+		c.handlePollError(err)
+		return true
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+	c.recordError(fmt.Sprintf("fetching stargazers of %s", repo), err)
+	return false
+}
+
+func (c *MyPlugin) fetchInitialStars() {
+	repos, err := c.starWatchedRepos()
+	if err != nil {
 		return
 	}
 
-	for _, repo := range repos {
-		repoURL := fmt.Sprintf("https://api.github.com/repos/%s/stargazers", repo.FullName)
-		req, err := http.NewRequest("GET", repoURL, nil)
-		if err != nil {
-			continue
-		}
-		req.Header.Add("Authorization", "token "+c.githubToken)
-		req.Header.Add("Accept", "application/vnd.github.v3.star+json")
-		resp, err := client.Do(req)
-		if err != nil {
+	for _, result := range c.fetchStargazersOf(repos) {
+		c.rememberStargazerETag(result)
+		if isNotFound(result.err) {
+			c.infoLog("skipping stars, the repo was not found", "repo", result.repo)
 			continue
 		}
-
-		var stars []struct {
-			StarredAt time.Time `json:"starred_at"`
-			User      struct {
-				Login string `json:"login"`
-			} `json:"user"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&stars); err != nil {
-			resp.Body.Close()
+		if result.err != nil {
+			if c.stargazersFailed(result.repo, result.err) {
+				return
+			}
 			continue
 		}
-		resp.Body.Close()
-
-		for _, star := range stars {
-			starKey := fmt.Sprintf("%s:%s", repo.FullName, star.User.Login)
-			c.seenStars[starKey] = true
+		now := c.clock.Now()
+		for _, star := range result.list.stars {
+			c.seenStars.mark(starKey(result.repo, star.User.Login), now)
 		}
 	}
 }
 
 func (c *MyPlugin) Disable() error {
 	if c.enabled {
-		c.enabled = false
 		close(c.stopChannel)
+		c.mu.Lock()
+		c.enabled = false
+		c.cancelRequest()
+		c.mu.Unlock()
+		c.client.CloseIdleConnections()
+		// Messages held for quiet hours move into the saved state, and
+		// Enable takes them back from there.
+		c.pollMu.Lock()
+		if c.store != nil {
+			c.saveState()
+			c.quietHandler.take()
+		}
+		c.pollMu.Unlock()
+	}
+	for _, account := range c.accounts {
+		account.Disable()
 	}
 	return nil
 }
 
-func (c *MyPlugin) startPolling() {
-	ticker := time.NewTicker(c.pollInterval)
+func (c *MyPlugin) startPolling(replay []GithubNotification, stop <-chan struct{}) {
+	c.pollMu.Lock()
+	c.deliverReplay(replay, stop)
+	c.pollMu.Unlock()
+
+	interval, _, _ := c.nextPollInterval()
+	c.setCurrentInterval(interval)
+	ticker := c.clock.NewTicker(interval)
 	defer ticker.Stop()
+	reschedule := func() {
+		next, fromServer, failures := c.nextPollInterval()
+		if next == interval {
+			return
+		}
+		if failures > 0 {
+			c.infoLog("polls keep failing, backing off", "failures", failures, "retry_in", next.Round(time.Second))
+		} else if fromServer {
+			c.infoLog("GitHub asked to poll less often, backing off", "interval", next)
+		} else {
+			c.infoLog("polling interval restored", "interval", next)
+		}
+		interval = next
+		c.setCurrentInterval(interval)
+		ticker.Reset(interval)
+	}
 	for {
 		select {
-		case <-ticker.C:
-			c.checkNotifications()
-			if c.watchStars {
-				c.checkStars()
-			}
-		case <-c.stopChannel:
+		case <-ticker.C():
+			c.poll()
+			reschedule()
+		case <-c.intervalChanged:
+			reschedule()
+		case <-stop:
 			return
 		}
 	}
 }
 
-func (c *MyPlugin) checkNotifications() {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://api.github.com/notifications", nil)
-	if err != nil {
-		return
+func (c *MyPlugin) poll() {
+	c.pollMu.Lock()
+	watchConfigFile := c.watchConfigFile
+	c.pollMu.Unlock()
+	if watchConfigFile {
+		c.reloadConfigFileIfChanged()
 	}
-	req.Header.Add("Authorization", "token "+c.githubToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-	resp, err := client.Do(req)
-	if err != nil {
+	c.pollMu.Lock()
+	c.setPolling(true)
+	defer func() {
+		c.setPolling(false)
+		c.pollMu.Unlock()
+	}()
+	c.flushQuietHours()
+	if c.isPaused() {
 		return
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if c.catchUpAfterPause() {
 		return
 	}
-	var notifications []GithubNotification
-	if err := json.Unmarshal(body, &notifications); err != nil {
+	defer c.checkRateBudget()
+	c.checkNotifications()
+	if c.inOutage() {
 		return
 	}
+	if c.watchStars {
+		c.checkStars()
+	}
+	if c.watchUnreadCount {
+		c.checkUnreadCount()
+	}
+	if c.watchMentions {
+		c.scanMentions(true)
+	}
+	if c.watchWiki {
+		c.scanWikiEdits(true)
+	}
+	if len(c.watchThreads) > 0 {
+		c.scanWatchedThreads(true)
+	}
+	if c.watchCollaborators {
+		c.scanCollaborators()
+	}
+	if c.watchSponsors {
+		c.checkSponsors()
+	}
+	if c.watchForcePushes {
+		c.scanForcePushes()
+	}
+	if c.watchWorkflows {
+		c.scanWorkflowRuns(true)
+	}
+	if c.watchReleases {
+		c.scanReleases(true)
+	}
+	if c.watchFollowers {
+		c.scanFollowers(true)
+	}
+	if c.watchForks {
+		c.scanForks(true)
+	}
+	if c.watchSecurityAlerts {
+		c.scanSecurityAlerts(true)
+	}
+	if c.trafficDigest {
+		c.checkTrafficDigest()
+	}
+	if c.repoMinGap > 0 {
+		c.flushRepoCatchUps()
+	}
+}
 
-	for _, notification := range notifications {
-		if !c.seenNotifications[notification.ID] {
-			log.Printf("New notification found: %s", notification.ID)
-			c.seenNotifications[notification.ID] = true
-
-			notificationType := ""
-			switch notification.Subject.Type {
-			case "Issue":
-				notificationType = "Issue"
-			case "PullRequest":
-				notificationType = "PR"
-			case "Release":
-				notificationType = "Release"
-			case "Discussion":
-				notificationType = "Discussion"
-			default:
-				notificationType = notification.Subject.Type
-			}
+// fetchNotifications reads every page of /notifications. Only the first page
+// is requested conditionally; changed is false when it was not modified. If
+// a later page fails, the pages read so far are returned and the ETag is
+// dropped so the next poll fetches everything again.
+func (c *MyPlugin) fetchNotifications() (notifications []GithubNotification, changed bool, err error) {
+	return c.fetchNotificationsSince(time.Time{})
+}
 
-			msg := &plugin.Message{
-				Title:    fmt.Sprintf("[%s] %s", notificationType, notification.Subject.Title),
-				Message:  fmt.Sprintf("New %s notification in %s", notificationType, notification.Repository.FullName),
-				Priority: 2,
-				Extras: map[string]interface{}{
-					"client::notification": map[string]interface{}{
-						"click": map[string]interface{}{
-							"url": notification.Subject.URL,
-						},
-					},
-				},
-			}
-			if err := c.msgHandler.SendMessage(*msg); err != nil {
-				log.Printf("error sending github notification: %v", err)
-			} else {
-				log.Printf("sent github notification: %s", notification.Subject.Title)
-			}
+// fetchNotificationsSince lists the threads updated after since, or all of
+// them when since is zero. Only the complete list is cached by its ETag; the
+// later polls compare against it and escalation needs every unread thread.
+func (c *MyPlugin) fetchNotificationsSince(since time.Time) (notifications []GithubNotification, changed bool, err error) {
+	const accept = "application/vnd.github.v3+json"
+	endpoint := c.baseURL + "/notifications?per_page=50"
+	if c.includeRead {
+		endpoint += "&all=true"
+	}
+	etag := &c.notificationsETag
+	if !since.IsZero() {
+		endpoint += "&since=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+		etag = new(string)
+	}
+	changed, next, err := c.getJSONIfChanged(endpoint, accept, etag, &notifications)
+	if err != nil || next == "" {
+		return uniqueThreads(notifications), changed, err
+	}
+	rest, err := fetchRemainingPages[GithubNotification](c, endpoint, next, 1, accept)
+	notifications = append(notifications, rest...)
+	if err != nil {
+		c.recordError("fetching further notification pages", err)
+		*etag = ""
+	}
+	return uniqueThreads(notifications), true, nil
+}
+
+// unreadOnly drops the read threads that includeRead adds to the list.
+func (c *MyPlugin) unreadOnly(notifications []GithubNotification) []GithubNotification {
+	if !c.includeRead {
+		return notifications
+	}
+	var unread []GithubNotification
+	for _, n := range notifications {
+		if n.Unread {
+			unread = append(unread, n)
 		}
 	}
+	return unread
 }
 
-func (c *MyPlugin) checkStars() {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://api.github.com/user/repos", nil)
-	if err != nil {
+// uniqueThreads drops repeated threads, keeping the first. A thread updated
+// while the pages were read moves to the front and can show up on two pages.
+func uniqueThreads(notifications []GithubNotification) []GithubNotification {
+	seen := make(map[string]bool, len(notifications))
+	return slices.DeleteFunc(notifications, func(n GithubNotification) bool {
+		if seen[n.ID] {
+			return true
+		}
+		seen[n.ID] = true
+		return false
+	})
+}
+
+func (c *MyPlugin) checkNotifications() {
+	if !c.readsNotifications() {
 		return
 	}
-	req.Header.Add("Authorization", "token "+c.githubToken)
-	req.Header.Add("Accept", "application/vnd.github.v3+json")
-	resp, err := client.Do(req)
+	notifications, changed, err := c.fetchNotificationsSince(c.notificationsSince)
 	if err != nil {
+		c.handlePollError(err)
 		return
 	}
-	defer resp.Body.Close()
-	var repos []struct {
-		FullName string `json:"full_name"`
+	c.notificationsSince = time.Time{}
+	// Snooze expiry and escalation still need the list when nothing changed.
+	if changed {
+		c.lastNotifications = notifications
+	} else {
+		notifications = c.lastNotifications
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-		return
+	c.pollSucceeded()
+	c.endOutage()
+	c.lastCheckTime = c.clock.Now()
+	defer c.saveState()
+
+	for _, id := range c.expireSnoozes() {
+		if c.snoozeRenotify {
+			delete(c.seenNotifications, id)
+		}
 	}
+	now := c.clock.Now()
+	defer func() {
+		if n := c.seenNotifications.evict(now.Add(-c.seenTTL)); n > 0 {
+			c.infoLog("forgot notification threads no longer listed", "count", n, "ttl", c.seenTTL)
+		}
+	}()
 
-	for _, repo := range repos {
-		repoURL := fmt.Sprintf("https://api.github.com/repos/%s/stargazers", repo.FullName)
-		req, err := http.NewRequest("GET", repoURL, nil)
-		if err != nil {
+	readAllAt := c.getReadAllAt()
+	newThisPoll := make(map[string]bool)
+	filter := c.activeFilter()
+	var pending []pendingNotification
+	for _, notification := range notifications {
+		if c.seenNotifications.has(notification.ID) {
+			// Still listed, so not due for eviction.
+			c.seenNotifications.mark(notification.ID, now)
+			c.debugLog("skipping notification", "id", notification.ID, "reason", "seen")
 			continue
 		}
-		req.Header.Add("Authorization", "token "+c.githubToken)
-		req.Header.Add("Accept", "application/vnd.github.v3.star+json")
-		resp, err := client.Do(req)
-		if err != nil {
+		if c.isSnoozed(notification.ID) {
+			c.debugLog("skipping notification", "id", notification.ID, "reason", "snoozed")
+			continue
+		}
+		if !readAllAt.IsZero() && !notification.UpdatedAt.After(readAllAt) {
+			c.seenNotifications.mark(notification.ID, now)
+			c.debugLog("skipping notification", "id", notification.ID, "reason", "read")
 			continue
 		}
+		c.debugLog("new notification", "id", notification.ID, "repo", notification.Repository.FullName, "type", notification.Subject.Type)
+		c.seenNotifications.mark(notification.ID, now)
+		newThisPoll[notification.ID] = true
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			resp.Body.Close()
+		priority, vipActor, skip := c.classifyNotification(notification, filter)
+		if skip != "" {
+			c.trackUnreadThread(notification.ID, priority, true)
+			c.debugLog("skipping notification", "id", notification.ID, "reason", skip)
 			continue
 		}
-		resp.Body.Close()
 
-		var stars []struct {
-			StarredAt time.Time `json:"starred_at"`
-			User      struct {
-				Login string `json:"login"`
-			} `json:"user"`
+		notificationType, _ := notificationLabel(notification.Subject.Type)
+		if vipActor == "" && !c.allowRepoMessage(notification.Repository.FullName, priority) {
+			c.trackUnreadThread(notification.ID, priority, true)
+			c.infoLog("suppressed notification within the repo's minimum gap", "id", notification.ID, "repo", notification.Repository.FullName)
+			continue
 		}
 
-		if err := json.Unmarshal(body, &stars); err != nil {
+		if c.waitForReleaseAssets && notification.Subject.Type == "Release" && !c.releaseAssetsReady(notification) {
+			// Tracked once checkPendingReleases sends it.
+			c.holdRelease(notification, notificationType, priority)
 			continue
 		}
+		c.trackUnreadThread(notification.ID, priority, false)
 
-		for _, star := range stars {
-			starKey := fmt.Sprintf("%s:%s", repo.FullName, star.User.Login)
-			if !c.seenStars[starKey] {
-				log.Printf("New star detected: %s starred %s", star.User.Login, repo.FullName)
-				c.seenStars[starKey] = true
+		var details []string
+		if c.discussionComments && notification.Subject.Type == "Discussion" {
+			details, _ = c.newDiscussionActivity(notification, false)
+		}
+		if vipActor != "" {
+			details = append(details, "from @"+vipActor)
+		}
+		pending = append(pending, pendingNotification{notification, notificationType, priority, details, vipActor != ""})
+	}
+	var digest []pendingNotification
+	for _, p := range pending {
+		if p.vip || !c.digestMode {
+			c.sendPending(p)
+		} else {
+			digest = append(digest, p)
+		}
+	}
+	if len(digest) > c.digestThreshold {
+		c.sendDigest(digest)
+	} else {
+		for _, p := range digest {
+			c.sendPending(p)
+		}
+	}
+
+	if c.waitForReleaseAssets {
+		c.checkPendingReleases()
+	}
+	if c.discussionComments {
+		c.checkDiscussionComments(notifications, newThisPoll, filter)
+	}
+
+	if c.escalateUnread {
+		c.escalateUnreadThreads(c.unreadOnly(notifications), filter)
+	}
+}
+
+// classifyNotification decides whether a new notification is sent and at
+// which priority: that of its type, overridden by that of its repo and
+// raised to vipPriority for VIP actors. skip is why it is not sent: it is
+// filtered out, its repo is muted or its type is unknown and suppressed.
+// Notifications from VIP actors are never skipped.
+func (c *MyPlugin) classifyNotification(n GithubNotification, filter *notificationFilter) (priority int, vipActor, skip string) {
+	if len(c.vipActors) > 0 {
+		vipActor = c.vipActor(n)
+	}
+	repoPriority, hasRepoPriority := c.repoPriority(n.Repository.FullName)
+	if vipActor == "" {
+		_, known := notificationLabel(n.Subject.Type)
+		switch {
+		case !filter.allows(n):
+			return 0, "", "filtered"
+		case repoPriority == repoMuted:
+			return 0, "", "muted"
+		case !known && c.suppressUnknownTypes:
+			return 0, "", "unknown type"
+		}
+	}
+
+	priority = c.typePriority(n.Subject.Type)
+	if c.showPullState {
+		if state := c.pullState(n); state != "" {
+			priority = c.pullStatePriority(state, priority)
+		}
+	}
+	if hasRepoPriority && repoPriority != repoMuted {
+		priority = repoPriority
+	}
+	if vipActor != "" {
+		priority = max(priority, c.vipPriority)
+	}
+	return priority, vipActor, ""
+}
+
+func (c *MyPlugin) sendNotification(notification GithubNotification, notificationType string, priority int, details ...string) {
+	c.sendPending(pendingNotification{notification: notification, typeLabel: notificationType, priority: priority, details: details})
+}
+
+// sendPending sends p as a message of its own.
+func (c *MyPlugin) sendPending(p pendingNotification) {
+	notification := p.notification
+	title, message := c.formatNotification(notification, p.typeLabel)
+	if c.showPullState {
+		if state := c.pullState(notification); state != "" {
+			title = c.withPullState(title, state)
+		}
+	}
+	if c.showReasonMarkers {
+		message = c.withReasonMarker(notification.Reason, message)
+	}
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  message,
+		Priority: p.priority,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": c.subjectWebURL(notification),
+				},
+			},
+		},
+	}
+	if c.showEngagement {
+		if line := c.engagementLine(notification); line != "" {
+			msg.Message = c.appendDetail(msg.Message, line)
+		}
+	}
+	if c.showDiffStat && notification.Subject.Type == "PullRequest" {
+		if line := c.diffStatLine(notification); line != "" {
+			msg.Message = c.appendDetail(msg.Message, line)
+		}
+	}
+	if c.showRepoContext {
+		if line := c.repoContextLine(notification); line != "" {
+			msg.Message = c.appendDetail(msg.Message, line)
+		}
+	}
+	if c.showReasonTime {
+		msg.Message = c.appendDetail(msg.Message, c.reasonAndTimeLine(notification))
+	}
+	for _, detail := range p.details {
+		msg.Message = c.appendDetail(msg.Message, detail)
+	}
+	if c.useMarkdown {
+		msg.Extras["client::display"] = map[string]interface{}{
+			"contentType": "text/markdown",
+		}
+	}
+	held, err := c.deliver(*msg, p.vip, notification)
+	switch {
+	case err != nil:
+		c.recordError("sending github notification", err)
+	case held:
+		c.infoLog("holding github notification for quiet hours", "title", notification.Subject.Title)
+	default:
+		c.infoLog("sent github notification", "title", notification.Subject.Title)
+	}
+}
+
+// notificationSent does the bookkeeping after notification was delivered,
+// on its own or as part of a digest, right away or after quiet hours.
+func (c *MyPlugin) notificationSent(notification GithubNotification) {
+	c.markRepoSent(notification.Repository.FullName)
+	if c.markAsRead {
+		if err := c.markThreadRead(notification.ID); err != nil {
+			c.recordError(fmt.Sprintf("marking notification %s as read", notification.ID), err)
+		}
+	}
+}
+
+func (c *MyPlugin) checkStars() {
+	repos, err := c.starWatchedRepos()
+	if err != nil {
+		return
+	}
+
+	changed := false
+	defer func() {
+		if changed {
+			c.saveState()
+		}
+	}()
+	now := c.clock.Now()
+	for _, result := range c.fetchStargazersOf(repos) {
+		repo, list := result.repo, result.list
+		c.rememberStargazerETag(result)
+		if isNotFound(result.err) {
+			if c.forgetStarsOf(repo) {
+				changed = true
+			}
+			continue
+		}
+		if result.err != nil {
+			if c.stargazersFailed(repo, result.err) {
+				return
+			}
+			// Nothing is known to be gone, so keep the repo's stars fresh.
+			c.seenStars.markPrefix(starKeyPrefix(repo), now)
+			continue
+		}
+		if !list.changed {
+			c.seenStars.markPrefix(starKeyPrefix(repo), now)
+			continue
+		}
+		c.checkUnstars(repo, list)
+		changed = true
+
+		for _, star := range list.stars {
+			key := starKey(repo, star.User.Login)
+			seen := c.seenStars.has(key)
+			c.seenStars.mark(key, now)
+			if !seen {
+				c.infoLog("new star detected", "login", star.User.Login, "repo", repo)
 
 				msg := &plugin.Message{
-					Title:    "New Star",
-					Message:  fmt.Sprintf("Repo %s received a star from %s", repo.FullName, star.User.Login),
-					Priority: 2,
+					Title:    c.withTypeEmoji("star", c.translate("star.title")),
+					Message:  c.translate("star.body", repo, star.User.Login),
+					Priority: c.typePriority("star"),
 					Extras: map[string]interface{}{
 						"client::notification": map[string]interface{}{
 							"click": map[string]interface{}{
-								"url": "https://github.com/" + repo.FullName,
+								"url": c.webURL(repo),
 							},
 						},
 					},
 				}
 				if err := c.msgHandler.SendMessage(*msg); err != nil {
-					log.Printf("error sending star notification: %v", err)
+					c.recordError("sending star notification", err)
 				} else {
-					log.Printf("sent star notification for repo %s", repo.FullName)
+					c.infoLog("sent star notification", "repo", repo)
 				}
 			}
 		}
 	}
+	// Only stars of repos that are no longer watched are left to expire.
+	if n := c.seenStars.evict(now.Add(-c.seenTTL)); n > 0 {
+		c.infoLog("forgot stars no longer listed", "count", n, "ttl", c.seenTTL)
+		changed = true
+	}
 }
 
 func GetGotifyPluginInfo() plugin.Info {
@@ -363,23 +1707,84 @@ func GetGotifyPluginInfo() plugin.Info {
 
 func NewGotifyPluginInstance(ctx plugin.UserContext) plugin.Plugin {
 	return &MyPlugin{
-		ctx:          ctx,
-		pollInterval: 60 * time.Second,
-		enabled:      false,
-		appID:        ctx.ID,
+		ctx:                 ctx,
+		pollInterval:        60 * time.Second,
+		requestTimeout:      30 * time.Second,
+		intervalChanged:     make(chan struct{}, 1),
+		enabled:             false,
+		unknownTypePriority: 2,
+		maxPages:            10,
+		repoAffiliation:     "owner,collaborator,organization_member",
+		baseURL:             "https://api.github.com",
+		client:              &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		clock:               realClock{},
+		unreadCount:         -1,
+		replayThrottle:      time.Second,
+		userRetryDelay:      2 * time.Second,
+		redactor:            &redactor{},
 	}
 }
 
 func (c *MyPlugin) SetMessageHandler(h plugin.MessageHandler) {
-	c.msgHandler = h
+	c.rawHandler = h
+	c.applyMessageHandler()
 }
 
 func (c *MyPlugin) ApplyConfig(config any) error {
 	return c.ValidateAndSetConfig(config)
 }
 
+func (c *MyPlugin) setCurrentInterval(interval time.Duration) {
+	c.mu.Lock()
+	c.currentInterval = interval
+	c.mu.Unlock()
+}
+
+// statusSummary renders the state of polling as a Markdown list for
+// GetDisplay.
+func (c *MyPlugin) statusSummary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := "disabled"
+	if c.enabled {
+		state = "enabled"
+	}
+	lines := []string{"- Polling: " + state}
+	if c.enabled && c.currentInterval > 0 {
+		lines[0] += fmt.Sprintf(", every %s", c.currentInterval.Round(time.Second))
+	}
+	if c.lastSuccess.IsZero() {
+		lines = append(lines, "- Last successful check: never")
+	} else {
+		lines = append(lines, "- Last successful check: "+c.formatTime(c.lastSuccess))
+	}
+	if c.lastError != nil {
+		lines = append(lines, fmt.Sprintf("- Last error: %s: %s (%s)", c.lastError.Op, c.lastError.Message, c.formatTime(c.lastError.Time)))
+	}
+	if core := c.rateWindow(rateResourceCore); core.remaining >= 0 && !core.reset.IsZero() {
+		lines = append(lines, fmt.Sprintf("- Rate limit: %s requests left until %s", rateBudget(core.remaining, core.limit), c.formatTime(core.reset)))
+	}
+	lines = append(lines, fmt.Sprintf("- Messages sent: %d", c.messagesSent))
+	return strings.Join(lines, "\n")
+}
+
 func (c *MyPlugin) GetDisplay(location *url.URL) string {
-	return "Configure your GitHub token and polling interval below to receive notifications"
+	display := "Configure your GitHub token and polling interval below to receive notifications"
+	display += "\n\n" + c.statusSummary()
+	if pausedAt := c.getPausedAt(); !pausedAt.IsZero() {
+		display += fmt.Sprintf("\n\n**Polling is paused** since %s. Send `POST %sresume` to continue.",
+			c.formatTime(pausedAt), c.webhookBasePath)
+	}
+	allowManagement, _, githubSecret := c.webhookSecrets()
+	if c.webhookBasePath != "" && allowManagement {
+		display += fmt.Sprintf("\n\nSend `POST %stest` with the `X-Webhook-Secret` header to check that messages arrive.",
+			c.webhookBasePath)
+	}
+	if githubSecret != "" {
+		display += fmt.Sprintf("\n\nGitHub webhooks (content type `application/json`) are accepted at `%sgithub`.",
+			c.webhookBasePath)
+	}
+	return c.redactor.redact(display)
 }
 
 func main() {
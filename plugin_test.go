@@ -1,10 +1,17 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gotify/plugin-api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAPICompatibility(t *testing.T) {
@@ -13,3 +20,214 @@ func TestAPICompatibility(t *testing.T) {
 	assert.Implements(t, (*plugin.Configurer)(nil), new(MyPlugin))
 	assert.Implements(t, (*plugin.Webhooker)(nil), new(MyPlugin))
 }
+
+const checkSuiteNotification = `[{"id":"9","repository":{"full_name":"octocat/hello-world"},` +
+	`"subject":{"title":"CI finished","type":"CheckSuite","url":""},"updated_at":"2024-05-01T10:00:00Z"}]`
+
+func TestUnknownTypePriority(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"unknownTypePriority": 1})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(checkSuiteNotification))
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[CheckSuite] CI finished", msgs[0].Title)
+	assert.Equal(t, 1, msgs[0].Priority)
+}
+
+func TestSuppressUnknownTypes(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"suppressUnknownTypes": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(checkSuiteNotification))
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages())
+}
+
+func TestUnknownTypePriorityRange(t *testing.T) {
+	p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+	err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "unknownTypePriority": 11})
+	assert.Error(t, err)
+}
+
+func TestRequestTimeoutMustBePositive(t *testing.T) {
+	for _, timeout := range []int{0, -5} {
+		p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+		err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": 60, "requestTimeout": timeout})
+		assert.Error(t, err, "requestTimeout %d", timeout)
+	}
+}
+
+func TestRequestTimeoutAbortsHungRequests(t *testing.T) {
+	srv := newFixtureServer(t)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"requestTimeout": 1})
+
+	start := time.Now()
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Less(t, time.Since(start), 3*time.Second)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	require.NotNil(t, p.lastError)
+	assert.Equal(t, errorKindNetwork, p.lastError.Kind)
+}
+
+func TestDisableAbortsPollInFlight(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/first"},{"full_name":"octocat/second"}]`))
+	var polling atomic.Bool
+	started := make(chan struct{})
+	srv.handle("/repos/octocat/first/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		if polling.Load() {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		serveJSON(`[]`)(w, r)
+	})
+	var secondFetches atomic.Int32
+	srv.handle("/repos/octocat/second/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		secondFetches.Add(1)
+		serveJSON(`[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"alice"}}]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "requestTimeout": 60, "starWorkers": 1})
+	require.NoError(t, p.Enable())
+	secondFetches.Store(0)
+
+	polling.Store(true)
+	done := make(chan struct{})
+	go func() {
+		p.poll()
+		close(done)
+	}()
+	<-started
+	require.NoError(t, p.Disable())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the poll kept running after Disable")
+	}
+	assert.Zero(t, secondFetches.Load(), "the remaining repos are not fetched")
+	assert.Empty(t, rec.Messages())
+	assert.ErrorIs(t, p.msgHandler.SendMessage(plugin.Message{Message: "late"}), errDisabled)
+	assert.Empty(t, rec.Messages())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	assert.Nil(t, p.lastError, "an aborted request is not an error")
+}
+
+func TestPollDuringConfigReload(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications.json")
+	srv.serveFixture("/user/repos", "user_repos.json")
+	srv.serveFixture("/repos/octocat/hello-world/stargazers", "stargazers.json")
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			p.poll()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, p.ApplyConfig(map[string]interface{}{
+			"token": "test-token", "apiBaseURL": srv.URL, "interval": 30 + i, "watchStars": i%2 == 0,
+		}))
+	}
+	<-done
+}
+
+func TestIntervalValidation(t *testing.T) {
+	for _, interval := range []int{0, -60, 9, 86401} {
+		p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+		err := p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": interval})
+		assert.ErrorContains(t, err, "interval must be between", "interval %d", interval)
+	}
+	for _, interval := range []int{10, 60, 86400} {
+		p := NewGotifyPluginInstance(plugin.UserContext{}).(*MyPlugin)
+		require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "interval": interval}))
+		assert.Equal(t, time.Duration(interval)*time.Second, p.pollInterval)
+	}
+}
+
+func TestGetDisplayShowsLiveStatus(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC).Unix()))
+		serveJSON(`[]`)(w, r)
+	})
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 120, "notifyOnError": true})
+	clk := newFakeClock()
+	p.clock = clk
+	assert.Contains(t, p.GetDisplay(nil), "- Polling: disabled")
+	assert.Contains(t, p.GetDisplay(nil), "- Last successful check: never")
+
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+	p.pollSucceeded()
+	p.handlePollError(errors.New("boom"))
+
+	display := p.GetDisplay(nil)
+	assert.Contains(t, display, "- Polling: enabled, every 2m0s")
+	assert.Contains(t, display, "- Last successful check: 2024-05-01 12:00 UTC")
+	assert.Contains(t, display, "- Last error: polling GitHub: boom (2024-05-01 12:00 UTC)")
+	assert.Contains(t, display, "- Rate limit: 4321 of 5000 requests left until 2024-05-01 13:00 UTC")
+	assert.Contains(t, display, "- Messages sent: 1", "the error alert")
+}
+
+func TestIncludeReadTogglesAllParameter(t *testing.T) {
+	for _, includeRead := range []bool{false, true} {
+		srv := newFixtureServer(t)
+		var mu sync.Mutex
+		var all []string
+		list := `[]`
+		srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			all = append(all, r.URL.Query().Get("all"))
+			body := list
+			mu.Unlock()
+			serveJSON(body)(w, r)
+		})
+		p, rec := newTestPlugin(t, srv, map[string]interface{}{"includeRead": includeRead})
+		require.NoError(t, p.Enable())
+
+		mu.Lock()
+		list = `[{"id":"1","unread":false,"repository":{"full_name":"octocat/hello-world"},` +
+			`"subject":{"title":"Already read","type":"Issue","url":""},"updated_at":"2024-05-01T10:00:00Z"}]`
+		mu.Unlock()
+		p.checkNotifications()
+		p.checkNotifications()
+		p.Disable()
+
+		mu.Lock()
+		want := ""
+		if includeRead {
+			want = "true"
+		}
+		for _, got := range all {
+			assert.Equal(t, want, got, "includeRead=%v", includeRead)
+		}
+		mu.Unlock()
+		assert.Len(t, rec.Messages(), 1, "a read thread listed on every poll is sent once")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+const (
+	// quietBatch holds messages during quiet hours and delivers them, in
+	// order, on the first poll after quiet hours end.
+	quietBatch = "batch"
+	// quietLower delivers messages right away at priority 0, which Gotify
+	// clients show without sound or vibration.
+	quietLower = "lower"
+)
+
+// quietQueueLimit caps how many messages are held during quiet hours; the
+// oldest are dropped beyond it and the drop is recorded as an error.
+const quietQueueLimit = 200
+
+// heldMessage is a message held during quiet hours. Notifications are the
+// GitHub notifications it reports; their bookkeeping waits for delivery.
+type heldMessage struct {
+	Message       plugin.Message       `json:"message"`
+	Notifications []GithubNotification `json:"notifications,omitempty"`
+}
+
+// quietWindow is a daily window given in minutes after midnight. A window
+// with start after end spans midnight.
+type quietWindow struct {
+	start, end int
+	location   *time.Location
+	mode       string
+}
+
+// parseClockTime parses "HH:MM" into minutes after midnight.
+func parseClockTime(option, value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q must be a time like 22:30", option, value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseQuietHours validates the quiet hours options. ok is false when quiet
+// hours are not configured.
+func parseQuietHours(conf Config, location *time.Location) (window quietWindow, ok bool, err error) {
+	if conf.QuietStart == "" && conf.QuietEnd == "" {
+		return quietWindow{}, false, nil
+	}
+	if conf.QuietStart == "" || conf.QuietEnd == "" {
+		return quietWindow{}, false, fmt.Errorf("quietStart and quietEnd must be set together")
+	}
+	if window.start, err = parseClockTime("quietStart", conf.QuietStart); err != nil {
+		return quietWindow{}, false, err
+	}
+	if window.end, err = parseClockTime("quietEnd", conf.QuietEnd); err != nil {
+		return quietWindow{}, false, err
+	}
+	if window.start == window.end {
+		return quietWindow{}, false, fmt.Errorf("quietStart and quietEnd must differ")
+	}
+	switch conf.QuietMode {
+	case quietBatch, quietLower:
+	default:
+		return quietWindow{}, false, fmt.Errorf("unknown quietMode %q, expected %q or %q", conf.QuietMode, quietBatch, quietLower)
+	}
+	window.location = location
+	window.mode = conf.QuietMode
+	return window, true, nil
+}
+
+func (w quietWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// quietHandler applies quiet hours to every message of an account. It wraps
+// the account's labeled and rate limited handler, so held messages are
+// counted against the send limit when they are delivered.
+type quietHandler struct {
+	plugin *MyPlugin
+	window quietWindow
+	inner  plugin.MessageHandler
+
+	mu    sync.Mutex
+	queue []heldMessage
+}
+
+func (h *quietHandler) SendMessage(msg plugin.Message) error {
+	_, err := h.send(heldMessage{Message: msg})
+	return err
+}
+
+// send delivers m, or holds it when quiet hours batch messages. held reports
+// the latter.
+func (h *quietHandler) send(m heldMessage) (held bool, err error) {
+	if !h.window.contains(h.plugin.clock.Now()) {
+		return false, h.inner.SendMessage(m.Message)
+	}
+	if h.window.mode == quietLower {
+		m.Message.Priority = 0
+		return false, h.inner.SendMessage(m.Message)
+	}
+	h.mu.Lock()
+	h.queue = append(h.queue, m)
+	dropped := len(h.queue) - quietQueueLimit
+	if dropped > 0 {
+		h.queue = h.queue[dropped:]
+	}
+	h.mu.Unlock()
+	if dropped > 0 {
+		h.plugin.recordError("holding messages during quiet hours",
+			fmt.Errorf("more than %d messages held, dropped the oldest %d", quietQueueLimit, dropped))
+	}
+	return true, nil
+}
+
+// take removes and returns the held messages. It is safe on a nil handler.
+func (h *quietHandler) take() []heldMessage {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	queue := h.queue
+	h.queue = nil
+	return queue
+}
+
+// snapshot returns a copy of the held messages. It is safe on a nil handler.
+func (h *quietHandler) snapshot() []heldMessage {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.queue)
+}
+
+// restore puts messages saved before the plugin was disabled in front of
+// those held since.
+func (h *quietHandler) restore(queue []heldMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.queue = append(slices.Clone(queue), h.queue...)
+}
+
+// flush delivers the held messages once quiet hours are over.
+func (h *quietHandler) flush() {
+	if h.window.contains(h.plugin.clock.Now()) {
+		return
+	}
+	queue := h.take()
+	if len(queue) > 0 {
+		h.plugin.infoLog("quiet hours ended, delivering held messages", "count", len(queue))
+	}
+	h.plugin.deliverHeld(queue, h.inner)
+}
+
+// deliverHeld sends messages held during quiet hours through handler and
+// does the bookkeeping of the notifications each one reports.
+func (c *MyPlugin) deliverHeld(queue []heldMessage, handler plugin.MessageHandler) {
+	for _, m := range queue {
+		if err := handler.SendMessage(m.Message); err != nil {
+			c.recordError("sending message held during quiet hours", err)
+			continue
+		}
+		for _, notification := range m.Notifications {
+			c.notificationSent(notification)
+		}
+	}
+}
+
+// deliver sends msg through quiet hours, or around them when vip is set, and
+// does the bookkeeping of notifications once msg went out. held reports that
+// msg waits for quiet hours to end; the bookkeeping waits with it.
+func (c *MyPlugin) deliver(msg plugin.Message, vip bool, notifications ...GithubNotification) (held bool, err error) {
+	switch {
+	case vip:
+		err = c.vipHandler.SendMessage(msg)
+	case c.quietHandler != nil:
+		held, err = c.quietHandler.send(heldMessage{Message: msg, Notifications: notifications})
+	default:
+		err = c.msgHandler.SendMessage(msg)
+	}
+	if err != nil || held {
+		return held, err
+	}
+	for _, notification := range notifications {
+		c.notificationSent(notification)
+	}
+	return false, nil
+}
+
+// restoreQuietQueue takes back the messages held when the plugin was last
+// disabled. Without quiet hours they are delivered right away.
+func (c *MyPlugin) restoreQuietQueue(queue []heldMessage) {
+	if len(queue) == 0 {
+		return
+	}
+	if c.quietHandler != nil {
+		c.quietHandler.restore(queue)
+		return
+	}
+	if c.msgHandler != nil {
+		c.deliverHeld(queue, c.msgHandler)
+	}
+}
+
+// flushQuietHours delivers what was held during quiet hours, if they ended.
+func (c *MyPlugin) flushQuietHours() {
+	if c.quietHandler != nil {
+		c.quietHandler.flush()
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuietWindowSpansMidnight(t *testing.T) {
+	w := quietWindow{start: 22 * 60, end: 7*60 + 30, location: time.UTC}
+	at := func(hour, minute int) time.Time { return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC) }
+	assert.True(t, w.contains(at(23, 0)))
+	assert.True(t, w.contains(at(0, 15)))
+	assert.True(t, w.contains(at(7, 29)))
+	assert.False(t, w.contains(at(7, 30)))
+	assert.False(t, w.contains(at(12, 0)))
+	assert.False(t, w.contains(at(21, 59)))
+}
+
+func TestQuietHoursBatchUntilTheyEnd(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	// The fake clock starts at 12:00 UTC, which is 14:00 in Berlin.
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"quietStart": "13:30",
+		"quietEnd":   "15:00",
+		"timezone":   "Europe/Berlin",
+	})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "First")+`,`+notificationJSON("2", "Second")+`]`))
+	p.poll()
+	assert.Empty(t, rec.Messages(), "messages are held during quiet hours")
+	assert.True(t, p.seenNotifications.has("1"))
+
+	clk.Advance(time.Hour)
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2, "held messages are delivered once quiet hours end")
+	assert.Equal(t, "[Issue] First", msgs[0].Title)
+	assert.Equal(t, "[Issue] Second", msgs[1].Title)
+}
+
+func TestQuietHoursMarkReadOnDeliveryAndSurviveDisable(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	var mu sync.Mutex
+	var patched []string
+	srv.handle("/notifications/threads/1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		patched = append(patched, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusResetContent)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"quietStart": "13:30",
+		"quietEnd":   "15:00",
+		"timezone":   "Europe/Berlin",
+		"markAsRead": true,
+	})
+	clk := newFakeClock()
+	p.clock = clk
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.Enable())
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "First")+`]`))
+	p.poll()
+	assert.Empty(t, rec.Messages())
+	mu.Lock()
+	assert.Empty(t, patched, "held notifications stay unread on GitHub")
+	mu.Unlock()
+
+	require.NoError(t, p.Disable())
+	assert.Empty(t, p.quietHandler.snapshot(), "the held message moved into the saved state")
+	srv.handle("/notifications", serveJSON(`[]`))
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Len(t, p.quietHandler.snapshot(), 1, "the held message is restored")
+
+	clk.Advance(time.Hour)
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[Issue] First", msgs[0].Title)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"PATCH /notifications/threads/1"}, patched, "marked as read once delivered")
+}
+
+func TestQuietHoursOverflowIsRecorded(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"quietStart": "11:00", "quietEnd": "13:00"})
+	p.clock = newFakeClock()
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	for i := 0; i <= quietQueueLimit; i++ {
+		require.NoError(t, p.quietHandler.SendMessage(plugin.Message{Title: "held"}))
+	}
+	assert.Len(t, p.quietHandler.snapshot(), quietQueueLimit)
+	errs := p.getRecentErrors()
+	require.NotEmpty(t, errs)
+	assert.Equal(t, "holding messages during quiet hours", errs[0].Op)
+}
+
+func TestQuietHoursOutsideWindowDeliverNormally(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"quietStart": "22:00", "quietEnd": "07:00"})
+	p.clock = newFakeClock()
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Noon")+`]`))
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 2, msgs[0].Priority)
+}
+
+func TestQuietHoursLowerMode(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"quietStart": "11:00", "quietEnd": "13:00", "quietMode": quietLower})
+	p.clock = newFakeClock()
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Noon")+`]`))
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, 0, msgs[0].Priority)
+}
+
+func TestVIPBreaksThroughQuietHours(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/issues/1", serveJSON(`{"user":{"login":"TheBoss"}}`))
+	srv.handle("/repos/octocat/hello-world/issues/2", serveJSON(`{"user":{"login":"someone"}}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"quietStart": "11:00", "quietEnd": "13:00", "vipActors": "theboss"})
+	p.clock = newFakeClock()
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[`+
+		`{"id":"1","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Urgent","type":"Issue",`+
+		`"url":"`+srv.URL+`/repos/octocat/hello-world/issues/1"}},`+
+		`{"id":"2","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Later","type":"Issue",`+
+		`"url":"`+srv.URL+`/repos/octocat/hello-world/issues/2"}}]`))
+	p.poll()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "only the VIP message skips quiet hours")
+	assert.Equal(t, "[Issue] Urgent", msgs[0].Title)
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.Len(t, p.quietHandler.take(), 1, "the other message is held")
+}
+
+func TestQuietHoursValidation(t *testing.T) {
+	for _, conf := range []Config{
+		{QuietStart: "22:00", QuietMode: quietBatch},
+		{QuietStart: "25:00", QuietEnd: "07:00", QuietMode: quietBatch},
+		{QuietStart: "07:00", QuietEnd: "07:00", QuietMode: quietBatch},
+		{QuietStart: "22:00", QuietEnd: "07:00", QuietMode: "drop"},
+	} {
+		_, _, err := parseQuietHours(conf, time.UTC)
+		assert.Error(t, err, "%+v", conf)
+	}
+	_, ok, err := parseQuietHours(Config{QuietMode: quietBatch}, time.UTC)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+const (
+	// rateLimitReserve is the number of requests left in the window at which
+	// all fetches hold off until the quota resets.
+	rateLimitReserve = 5
+	// rateLimitMaxBackoff caps the exponential backoff used for rate limit
+	// responses that carry no usable reset time.
+	rateLimitMaxBackoff = 15 * time.Minute
+	// rateResourceCore is the quota of the REST API, which drives the rate
+	// limit warning and slows polling down.
+	rateResourceCore = "core"
+)
+
+// rateWindow is what GitHub last reported for one rate limit resource.
+type rateWindow struct {
+	remaining    int
+	limit        int
+	reset        time.Time
+	hits         int
+	blockedUntil time.Time
+}
+
+// rateWindow returns the window of resource. The caller holds c.mu.
+func (c *MyPlugin) rateWindow(resource string) *rateWindow {
+	if c.rates == nil {
+		c.rates = make(map[string]*rateWindow)
+	}
+	w, ok := c.rates[resource]
+	if !ok {
+		w = &rateWindow{remaining: -1}
+		c.rates[resource] = w
+	}
+	return w
+}
+
+// rateResource names the rate limit resource req counts against. GitHub
+// confirms it in the X-RateLimit-Resource header of the response.
+func (c *MyPlugin) rateResource(req *http.Request) string {
+	path := req.URL.Path
+	if base, err := url.Parse(c.baseURL); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(base.Path, "/"))
+	}
+	switch {
+	case strings.HasPrefix(path, "/search/"):
+		return "search"
+	case strings.HasSuffix(path, "/graphql"):
+		return "graphql"
+	}
+	return rateResourceCore
+}
+
+// requestContext is the context every GitHub request is made with. It is
+// cancelled when the plugin is disabled.
+func (c *MyPlugin) requestContext() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.requestCtx == nil {
+		return context.Background()
+	}
+	return c.requestCtx
+}
+
+// do sends req through the shared client. Every watcher goes through here so
+// they all respect a single rate limit window instead of each retrying on
+// their own and tripping the limit again.
+func (c *MyPlugin) do(req *http.Request) (*http.Response, error) {
+	if err := c.waitForRateLimit(req); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		c.debugLog("github request failed", "method", req.Method, "url", req.URL.String(), "err", err)
+		return nil, networkError(req, err)
+	}
+	c.debugLog("github request", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode,
+		"duration", time.Since(start).Round(time.Millisecond))
+	decodeBody(resp)
+	c.recordRateLimit(resp)
+	c.recordPollInterval(resp)
+	c.checkSSO(resp)
+	return resp, nil
+}
+
+// userAgent identifies the plugin and its version to GitHub, as its API
+// guidelines ask, followed by the configured suffix if any.
+func userAgent(suffix string) string {
+	info := GetGotifyPluginInfo()
+	ua := info.Name + "/" + info.Version
+	if suffix = strings.TrimSpace(suffix); suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
+
+// setPolling sets polling and returns its previous value.
+func (c *MyPlugin) setPolling(polling bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.polling
+	c.polling = polling
+	return previous
+}
+
+// waitForRateLimit holds req until the block of its rate limit resource ends. A poll
+// releases pollMu while it waits; requests made alongside it, such as those
+// of the stargazer workers, wait with the lock kept.
+func (c *MyPlugin) waitForRateLimit(req *http.Request) error {
+	c.mu.Lock()
+	wait := c.rateWindow(c.rateResource(req)).blockedUntil.Sub(c.clock.Now())
+	polling := c.polling
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	c.infoLog("rate limit reached, holding request", "path", req.URL.Path, "wait", wait.Round(time.Second))
+	if polling {
+		// The wait can last until the quota resets; let configuration
+		// changes through in the meantime.
+		c.pollMu.Unlock()
+		defer c.pollMu.Lock()
+	}
+	select {
+	case <-c.clock.After(wait):
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// recordRateLimit updates the quota of the response's rate limit resource
+// from its headers. Once the quota is (nearly) exhausted, fetches against
+// that resource are blocked until it resets plus some jitter. Repeated rate
+// limit responses back off exponentially.
+func (c *MyPlugin) recordRateLimit(resp *http.Response) {
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	var reset time.Time
+	if v, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(v, 0)
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusForbidden && remainingErr == nil && remaining == 0
+
+	resource := resp.Header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = rateResourceCore
+		if resp.Request != nil {
+			resource = c.rateResource(resp.Request)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := c.rateWindow(resource)
+	if remainingErr == nil {
+		w.remaining = remaining
+		w.reset = reset
+	}
+	if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+		w.limit = limit
+	}
+
+	if !limited {
+		w.hits = 0
+		if remainingErr == nil && remaining <= rateLimitReserve && reset.After(c.clock.Now()) {
+			w.blockedUntil = reset.Add(rateLimitJitter())
+		}
+		return
+	}
+
+	w.hits++
+	backoff := time.Second << min(w.hits, 10)
+	if backoff > rateLimitMaxBackoff {
+		backoff = rateLimitMaxBackoff
+	}
+	until := c.clock.Now().Add(backoff)
+	if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		if t := c.clock.Now().Add(time.Duration(retryAfter) * time.Second); t.After(until) {
+			until = t
+		}
+	}
+	if reset.After(until) {
+		until = reset
+	}
+	w.blockedUntil = until.Add(rateLimitJitter())
+}
+
+func rateLimitJitter() time.Duration {
+	return time.Duration(rand.Int63n(int64(5 * time.Second)))
+}
+
+// recordPollInterval remembers the X-Poll-Interval GitHub sends with the
+// notifications endpoint. Responses without the header leave it alone.
+func (c *MyPlugin) recordPollInterval(resp *http.Response) {
+	v := resp.Header.Get("X-Poll-Interval")
+	if v == "" {
+		return
+	}
+	seconds, err := strconv.Atoi(v)
+	if err != nil || seconds < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.serverPollInterval = time.Duration(seconds) * time.Second
+	c.mu.Unlock()
+}
+
+// nextPollInterval is the configured interval, doubled while the rate limit
+// budget is low, or the one GitHub asked for when that is longer; fromServer
+// reports the latter. After failures
+// consecutive transient errors the interval doubles with each failure, up to
+// maxBackoff, plus up to a tenth of jitter so several instances do not retry
+// in lockstep.
+func (c *MyPlugin) nextPollInterval() (interval time.Duration, fromServer bool, failures int) {
+	c.pollMu.Lock()
+	configured, maxBackoff, warning := c.pollInterval, c.maxBackoff, c.rateLimitWarning
+	c.pollMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateBudgetLow(warning) {
+		configured *= 2
+	}
+	interval = configured
+	if c.serverPollInterval > configured {
+		interval, fromServer = c.serverPollInterval, true
+	}
+	if c.pollFailures == 0 {
+		return interval, fromServer, 0
+	}
+	backoff := interval << min(c.pollFailures, 16)
+	if backoff > maxBackoff {
+		backoff = max(maxBackoff, interval)
+	}
+	return backoff + backoffJitter(backoff), false, c.pollFailures
+}
+
+func backoffJitter(backoff time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(backoff)/10 + 1))
+}
+
+// rateBudgetLow reports whether fewer than warning requests are left in the
+// current core rate limit window. The caller holds c.mu.
+func (c *MyPlugin) rateBudgetLow(warning int) bool {
+	core := c.rateWindow(rateResourceCore)
+	return warning > 0 && core.remaining >= 0 && core.remaining < warning && core.reset.After(c.clock.Now())
+}
+
+// checkRateBudget sends a low priority warning, once per rate limit window,
+// when the core budget dropped below rateLimitWarning.
+func (c *MyPlugin) checkRateBudget() {
+	c.mu.Lock()
+	core := c.rateWindow(rateResourceCore)
+	if !c.rateBudgetLow(c.rateLimitWarning) || c.rateWarnedReset.Equal(core.reset) {
+		c.mu.Unlock()
+		return
+	}
+	c.rateWarnedReset = core.reset
+	remaining, limit, reset := core.remaining, core.limit, core.reset
+	c.mu.Unlock()
+
+	c.infoLog("rate limit budget low, polling less often", "remaining", remaining, "reset", reset)
+	msg := plugin.Message{
+		Title:    c.translate("ratelimit.title"),
+		Message:  c.translate("ratelimit.body", rateBudget(remaining, limit), c.formatTime(reset)),
+		Priority: 1,
+	}
+	if err := c.msgHandler.SendMessage(msg); err != nil {
+		c.recordError("sending rate limit warning", err)
+	}
+}
+
+// rateBudget renders the remaining requests, with the window's limit when
+// GitHub sent it.
+func rateBudget(remaining, limit int) string {
+	if limit > 0 {
+		return fmt.Sprintf("%d of %d", remaining, limit)
+	}
+	return fmt.Sprint(remaining)
+}
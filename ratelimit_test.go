@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitReserveBlocksAllFetchesUntilReset(t *testing.T) {
+	srv := newFixtureServer(t)
+	reset := time.Now().Add(2 * time.Second).Unix()
+	srv.handle("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "1")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset))
+		serveJSON(`[]`)(w, r)
+	})
+	p, _ := newTestPlugin(t, srv, nil)
+	core := p.rateWindow(rateResourceCore)
+
+	_, err := p.fetchUserRepos()
+	require.NoError(t, err)
+	assert.Equal(t, 1, core.remaining)
+	assert.False(t, core.blockedUntil.Before(time.Unix(reset, 0)))
+
+	core.blockedUntil = time.Now().Add(300 * time.Millisecond)
+	start := time.Now()
+	var notifications []GithubNotification
+	srv.handle("/notifications", serveJSON(`[]`))
+	require.NoError(t, p.getJSON(srv.URL+"/notifications", "application/json", &notifications))
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "other watchers wait for the shared window")
+}
+
+func TestRateLimitResponsesBackOffExponentially(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	core := p.rateWindow(rateResourceCore)
+
+	limited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	p.recordRateLimit(limited)
+	first := time.Until(core.blockedUntil)
+	p.recordRateLimit(limited)
+	p.recordRateLimit(limited)
+	third := time.Until(core.blockedUntil)
+	assert.Equal(t, 3, core.hits)
+	assert.Greater(t, third, first)
+
+	p.recordRateLimit(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	assert.Equal(t, 0, core.hits)
+}
+
+func (c *fakeClock) tickerInterval() time.Duration {
+	c.mu.Lock()
+	t := c.tickers[len(c.tickers)-1]
+	c.mu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interval
+}
+
+func TestPollIntervalHeaderSlowsPolling(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	pollInterval := ""
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		if pollInterval != "" {
+			w.Header().Set("X-Poll-Interval", pollInterval)
+		}
+		mu.Unlock()
+		serveJSON(`[]`)(w, r)
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return polls
+	}
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 60})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, 1, count())
+
+	mu.Lock()
+	pollInterval = "120"
+	mu.Unlock()
+	clk.Advance(60 * time.Second)
+	require.Eventually(t, func() bool { return clk.tickerInterval() == 120*time.Second }, time.Second, time.Millisecond)
+	assert.Equal(t, 2, count())
+
+	clk.Advance(60 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 2, count(), "the next poll waits for the interval GitHub asked for")
+
+	mu.Lock()
+	pollInterval = "30"
+	mu.Unlock()
+	clk.Advance(60 * time.Second)
+	require.Eventually(t, func() bool { return clk.tickerInterval() == 60*time.Second }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, count(), "polling returns to the configured interval")
+}
+
+func TestForbiddenRateLimitPausesPollingUntilReset(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	clk := newFakeClock()
+	reset := clk.Now().Add(10 * time.Minute)
+	limited := true
+	srv := newFixtureServer(t)
+	count := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+	respond := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[r.URL.Path]++
+			isLimited := limited
+			mu.Unlock()
+			if isLimited {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+				return
+			}
+			serveJSON(body)(w, r)
+		}
+	}
+	srv.handle("/notifications", respond(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"},{"full_name":"octocat/spoon-knife"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", respond(`[]`))
+	srv.handle("/repos/octocat/spoon-knife/stargazers", respond(`[]`))
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "starWorkers": 1})
+	p.clock = clk
+	p.stargazerETags = map[string]string{}
+
+	p.checkStars()
+	assert.Equal(t, 1, count("/repos/octocat/hello-world/stargazers"))
+	assert.Zero(t, count("/repos/octocat/spoon-knife/stargazers"), "stars stop at the exhausted limit")
+	assert.True(t, p.isPaused())
+	assert.True(t, reset.Equal(p.pausedUntil))
+
+	p.poll()
+	assert.Zero(t, count("/notifications"), "polling is paused until the reset")
+
+	mu.Lock()
+	limited = false
+	mu.Unlock()
+	clk.Advance(10*time.Minute + 6*time.Second)
+	assert.False(t, p.isPaused())
+	p.poll()
+	assert.Equal(t, 1, count("/notifications"))
+}
+
+func TestTransientFailuresBackOffExponentially(t *testing.T) {
+	var mu sync.Mutex
+	failing := false
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		serveJSON(`[]`)(w, r)
+	})
+	setFailing := func(v bool) {
+		mu.Lock()
+		failing = v
+		mu.Unlock()
+	}
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "maxBackoff": 300})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+
+	setFailing(true)
+	expect := func(base time.Duration) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			d := clk.tickerInterval()
+			return d >= base && d <= base+base/10
+		}, time.Second, time.Millisecond, "want %s plus jitter", base)
+	}
+	clk.Advance(60 * time.Second)
+	expect(120 * time.Second)
+	clk.Advance(clk.tickerInterval())
+	expect(240 * time.Second)
+	clk.Advance(clk.tickerInterval())
+	expect(300 * time.Second)
+
+	setFailing(false)
+	clk.Advance(clk.tickerInterval())
+	require.Eventually(t, func() bool { return clk.tickerInterval() == 60*time.Second }, time.Second, time.Millisecond)
+	assert.Equal(t, 0, p.pollFailures)
+}
+
+func TestBackoffNeverShortensTheInterval(t *testing.T) {
+	p := &MyPlugin{clock: newFakeClock(), pollInterval: time.Hour, maxBackoff: time.Minute, pollFailures: 3}
+	interval, _, failures := p.nextPollInterval()
+	assert.GreaterOrEqual(t, interval, time.Hour)
+	assert.Equal(t, 3, failures)
+}
+
+func TestLowRateBudgetWarnsOnceAndSlowsPolling(t *testing.T) {
+	clk := newFakeClock()
+	reset := clk.Now().Add(time.Hour)
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "50")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+		serveJSON(`[]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "rateLimitWarning": 100})
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.Eventually(t, func() bool { return clk.tickerCount() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 120*time.Second, clk.tickerInterval(), "polling slows down while the budget is low")
+
+	clk.Advance(120 * time.Second)
+	require.Eventually(t, func() bool { return len(rec.Messages()) == 1 }, time.Second, time.Millisecond)
+	msg := rec.Messages()[0]
+	assert.Equal(t, "GitHub rate limit running low", msg.Title)
+	assert.Equal(t, "50 of 5000 requests left until 2024-05-01 13:00 UTC, polling less often until then", msg.Message)
+	assert.Equal(t, 1, msg.Priority)
+
+	clk.Advance(120 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, rec.Messages(), 1, "the warning is sent once per window")
+
+	clk.Advance(time.Hour)
+	require.Eventually(t, func() bool { return clk.tickerInterval() == 60*time.Second }, time.Second, time.Millisecond,
+		"polling returns to the configured interval once the window reset")
+}
+
+func TestRateLimitWaitDoesNotBlockConfigChanges(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"interval": 60})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.mu.Lock()
+	p.rateWindow(rateResourceCore).blockedUntil = clk.Now().Add(time.Hour)
+	p.mu.Unlock()
+	polled := make(chan struct{})
+	go func() {
+		p.poll()
+		close(polled)
+	}()
+	require.Eventually(t, func() bool { return clk.timerCount() == 1 }, time.Second, time.Millisecond)
+
+	applied := make(chan error)
+	go func() {
+		applied <- p.ValidateAndSetConfig(map[string]interface{}{"token": "test-token", "apiBaseURL": srv.URL, "interval": 120})
+	}()
+	select {
+	case err := <-applied:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the configuration change waited for the rate limit")
+	}
+
+	clk.Advance(time.Hour)
+	select {
+	case <-polled:
+	case <-time.After(time.Second):
+		t.Fatal("the poll did not resume after the rate limit reset")
+	}
+}
+
+func TestStarWorkersWaitOutTheRateLimitWithoutReleasingPollMu(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	var repos []string
+	for i := range 4 {
+		repo := fmt.Sprintf("octocat/repo-%d", i)
+		repos = append(repos, repo)
+		srv.handle("/repos/"+repo+"/stargazers", serveJSON(`[]`))
+	}
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{
+		"watchStars":  true,
+		"starWorkers": 4,
+		"starRepos":   strings.Join(repos, ","),
+	})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "3")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(clk.Now().Add(time.Hour).Unix()))
+		serveJSON(`[]`)(w, r)
+	})
+	polled := make(chan struct{})
+	go func() {
+		p.poll()
+		close(polled)
+	}()
+	require.Eventually(t, func() bool { return clk.timerCount() == 4 }, time.Second, time.Millisecond,
+		"every worker waits for the reset")
+
+	clk.Advance(time.Hour + 10*time.Second)
+	select {
+	case <-polled:
+	case <-time.After(time.Second):
+		t.Fatal("the poll did not finish after the rate limit reset")
+	}
+}
+
+func TestSearchQuotaIsTrackedApartFromCore(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv := newFixtureServer(t)
+	srv.handle("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Resource", "search")
+		w.Header().Set("X-RateLimit-Remaining", "3")
+		w.Header().Set("X-RateLimit-Limit", "30")
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprint(reset.Unix()))
+		serveJSON(`{"items":[]}`)(w, r)
+	})
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"interval": 60, "rateLimitWarning": 100})
+
+	var result struct{}
+	require.NoError(t, p.getJSON(srv.URL+"/search/issues?q=mentions:octocat", "application/json", &result))
+	assert.False(t, p.rateWindow("search").blockedUntil.Before(reset), "the search quota is held back")
+	assert.Equal(t, -1, p.rateWindow(rateResourceCore).remaining)
+
+	p.checkRateBudget()
+	assert.Empty(t, rec.Messages(), "a low search quota is no reason to warn")
+	interval, _, _ := p.nextPollInterval()
+	assert.Equal(t, 60*time.Second, interval, "nor to poll less often")
+
+	start := time.Now()
+	var notifications []GithubNotification
+	require.NoError(t, p.getJSON(srv.URL+"/notifications", "application/json", &notifications))
+	assert.Less(t, time.Since(start), time.Second, "core requests are not held back")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// markAllRead marks every notification updated up to lastReadAt as read.
+// GitHub answers 205 when it is done and 202 when it will finish in the
+// background.
+func (c *MyPlugin) markAllRead(lastReadAt time.Time) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"last_read_at": lastReadAt.UTC().Format(time.RFC3339),
+		"read":         true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := c.newGitHubRequest(c.requestContext(), "PUT", c.baseURL+"/notifications", "application/vnd.github.v3+json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusResetContent && resp.StatusCode != http.StatusAccepted {
+		return resp.StatusCode, responseError(resp)
+	}
+
+	c.mu.Lock()
+	if lastReadAt.After(c.readAllAt) {
+		c.readAllAt = lastReadAt
+	}
+	c.mu.Unlock()
+	return resp.StatusCode, nil
+}
+
+// markThreadRead marks a single notification thread as read, which GitHub
+// confirms with 205 Reset Content.
+func (c *MyPlugin) markThreadRead(threadID string) error {
+	req, err := c.newGitHubRequest(c.requestContext(), "PATCH", c.baseURL+"/notifications/threads/"+url.PathEscape(threadID), "application/vnd.github.v3+json", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusResetContent {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (c *MyPlugin) getReadAllAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readAllAt
+}
+
+func (c *MyPlugin) handleReadAll(ctx *gin.Context) {
+	lastReadAt := c.clock.Now()
+	if v := ctx.Query("last_read_at"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "last_read_at must be an RFC3339 timestamp"})
+			return
+		}
+		lastReadAt = t
+	}
+
+	c.pollMu.Lock()
+	if !c.readsNotifications() {
+		c.pollMu.Unlock()
+		ctx.JSON(http.StatusConflict, gin.H{"error": "a GitHub App installation cannot read notifications"})
+		return
+	}
+	status, err := c.markAllRead(lastReadAt)
+	c.pollMu.Unlock()
+	if err != nil {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "githubStatus": status})
+		return
+	}
+	result := "done"
+	if status == http.StatusAccepted {
+		result = "accepted"
+	}
+	ctx.JSON(http.StatusOK, gin.H{"result": result, "lastReadAt": lastReadAt})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAllWebhook(t *testing.T) {
+	srv := newFixtureServer(t)
+	var put map[string]interface{}
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			b, _ := io.ReadAll(r.Body)
+			json.Unmarshal(b, &put)
+			w.WriteHeader(http.StatusResetContent)
+			return
+		}
+		serveJSON(`[]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	r := newWebhookRouter(p)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/read-all", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/read-all?last_read_at=2024-05-01T12:00:00Z", nil)
+	req.Header.Set("X-Webhook-Secret", "s3cret")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024-05-01T12:00:00Z", put["last_read_at"])
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "notifications read via read-all must not notify")
+}
+
+func TestManagementEndpointsDisabledByDefault(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	w := httptest.NewRecorder()
+	newWebhookRouter(p).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/read-all", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "allowManagement": true}))
+}
+
+type failingHandler struct{}
+
+func (failingHandler) SendMessage(plugin.Message) error {
+	return errors.New("gotify unavailable")
+}
+
+func TestMarkAsReadAfterDelivery(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	var mu sync.Mutex
+	var patched []string
+	markRead := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			patched = append(patched, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(status)
+		}
+	}
+	srv.handle("/notifications/threads/1", markRead(http.StatusResetContent))
+	srv.handle("/notifications/threads/2", markRead(http.StatusInternalServerError))
+	srv.handle("/notifications/threads/3", markRead(http.StatusResetContent))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"markAsRead": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON("["+notificationJSON("1", "First")+","+notificationJSON("2", "Second")+"]"))
+	p.checkNotifications()
+	assert.Len(t, rec.Messages(), 2, "a failed mark-read does not hold back other notifications")
+	mu.Lock()
+	assert.Equal(t, []string{"PATCH /notifications/threads/1", "PATCH /notifications/threads/2"}, patched)
+	patched = nil
+	mu.Unlock()
+	require.NotEmpty(t, p.getRecentErrors())
+	assert.Equal(t, "marking notification 2 as read", p.getRecentErrors()[0].Op)
+
+	p.SetMessageHandler(failingHandler{})
+	srv.handle("/notifications", serveJSON("["+notificationJSON("3", "Undelivered")+"]"))
+	p.checkNotifications()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Empty(t, patched, "undelivered notifications stay unread on GitHub")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type pendingRelease struct {
+	notification     GithubNotification
+	notificationType string
+	priority         int
+	since            time.Time
+}
+
+type releaseDetail struct {
+	Assets []struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	} `json:"assets"`
+}
+
+// releaseAssetsReady reports whether the release behind n has at least
+// minReleaseAssets fully uploaded assets.
+func (c *MyPlugin) releaseAssetsReady(n GithubNotification) bool {
+	if n.Subject.URL == "" {
+		return true
+	}
+	var detail releaseDetail
+	if err := c.getJSON(n.Subject.URL, "application/vnd.github.v3+json", &detail); err != nil {
+		c.recordError(fmt.Sprintf("fetching release %s", n.Subject.URL), err)
+		return false
+	}
+	uploaded := 0
+	for _, asset := range detail.Assets {
+		if asset.State == "uploaded" {
+			uploaded++
+		}
+	}
+	return uploaded >= c.minReleaseAssets
+}
+
+func (c *MyPlugin) holdRelease(n GithubNotification, notificationType string, priority int) {
+	c.infoLog("holding release notification until its assets are uploaded", "id", n.ID)
+	c.pendingReleases[n.ID] = &pendingRelease{
+		notification:     n,
+		notificationType: notificationType,
+		priority:         priority,
+		since:            c.clock.Now(),
+	}
+}
+
+// checkPendingReleases sends held release notifications once their assets
+// are available, or with a note once releaseAssetTimeout has passed.
+func (c *MyPlugin) checkPendingReleases() {
+	for id, pending := range c.pendingReleases {
+		if c.releaseAssetsReady(pending.notification) {
+			delete(c.pendingReleases, id)
+			c.trackUnreadThread(id, pending.priority, false)
+			c.sendNotification(pending.notification, pending.notificationType, pending.priority, c.translate("release.assetsReady"))
+			continue
+		}
+		if c.clock.Now().Sub(pending.since) >= c.releaseAssetTimeout {
+			delete(c.pendingReleases, id)
+			c.trackUnreadThread(id, pending.priority, false)
+			c.sendNotification(pending.notification, pending.notificationType, pending.priority, c.translate("release.assetsLate"))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForReleaseAssets(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/releases/7", serveJSON(`{"assets":[{"name":"app.tar.gz","state":"starter"}]}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"waitForReleaseAssets": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/notifications", serveJSON(`[{"id":"5","repository":{"full_name":"octocat/hello-world"},`+
+		`"subject":{"title":"v1.0.0","type":"Release","url":"`+srv.URL+`/repos/octocat/hello-world/releases/7"}}]`))
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "the release is held while assets upload")
+	require.Contains(t, p.pendingReleases, "5")
+
+	srv.handle("/repos/octocat/hello-world/releases/7", serveJSON(`{"assets":[{"name":"app.tar.gz","state":"uploaded"}]}`))
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[Release] v1.0.0", msgs[0].Title)
+	assert.Equal(t, "New Release notification in octocat/hello-world\nAssets are ready", msgs[0].Message)
+	assert.Empty(t, p.pendingReleases)
+}
+
+func TestReleaseAssetTimeout(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/repos/octocat/hello-world/releases/7", serveJSON(`{"assets":[]}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"waitForReleaseAssets": true})
+	p.pendingReleases = map[string]*pendingRelease{}
+
+	var n GithubNotification
+	n.ID = "5"
+	n.Subject.Title = "v1.0.0"
+	n.Subject.URL = srv.URL + "/repos/octocat/hello-world/releases/7"
+	p.holdRelease(n, "Release", 2)
+	p.pendingReleases["5"].since = time.Now().Add(-31 * time.Minute)
+	p.checkPendingReleases()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0].Message, "Assets are not available yet")
+}
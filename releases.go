@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gotify/plugin-api"
+)
+
+type release struct {
+	ID      int64  `json:"id"`
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+	Draft   bool   `json:"draft"`
+}
+
+// releaseWatchedRepos returns the repos named by releaseRepos, or every
+// watched repo when that is empty.
+func (c *MyPlugin) releaseWatchedRepos() ([]Repo, error) {
+	if len(c.releaseRepos) == 0 {
+		return c.watchedRepos()
+	}
+	repos := make([]Repo, len(c.releaseRepos))
+	for i, repo := range c.releaseRepos {
+		repos[i] = Repo{FullName: repo}
+	}
+	return repos, nil
+}
+
+// scanReleases looks for newly published releases among the latest releases
+// of every release-watched repo. Releases are keyed by repo and release ID,
+// so a re-tagged release is not reported twice. Repos whose releases the
+// token cannot read are skipped until the plugin is re-enabled.
+func (c *MyPlugin) scanReleases(notify bool) {
+	repos, err := c.releaseWatchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for release watch", err)
+		return
+	}
+
+	for _, repo := range repos {
+		if c.releasesForbidden[repo.FullName] {
+			continue
+		}
+		var releases []release
+		endpoint := fmt.Sprintf("%s/repos/%s/releases?per_page=10", c.baseURL, repo.FullName)
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &releases); err != nil {
+			if status := classifyError(err).StatusCode; status == http.StatusForbidden || status == http.StatusNotFound {
+				c.infoLog("skipping release watch, the releases are not readable", "repo", repo.FullName)
+				c.releasesForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching releases for %s", repo.FullName), err)
+			}
+			continue
+		}
+
+		// Releases are newest first; notify oldest first.
+		for i := len(releases) - 1; i >= 0; i-- {
+			rel := releases[i]
+			if rel.Draft {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", repo.FullName, rel.ID)
+			if c.seenReleases[key] {
+				continue
+			}
+			c.seenReleases[key] = true
+			if !notify {
+				continue
+			}
+
+			message := rel.TagName
+			if rel.Name != "" && rel.Name != rel.TagName {
+				message = fmt.Sprintf("%s (%s)", rel.Name, rel.TagName)
+			}
+			if c.releaseNotes && rel.Body != "" {
+				message += "\n" + snippet(rel.Body)
+			}
+			msg := &plugin.Message{
+				Title:    c.withTypeEmoji("Release", fmt.Sprintf("New release of %s", repo.FullName)),
+				Message:  message,
+				Priority: c.typePriority("Release"),
+				Extras: map[string]interface{}{
+					"client::notification": map[string]interface{}{
+						"click": map[string]interface{}{
+							"url": rel.HTMLURL,
+						},
+					},
+				},
+			}
+			if err := c.msgHandler.SendMessage(*msg); err != nil {
+				c.recordError("sending release notification", err)
+			} else {
+				c.infoLog("sent release notification", "repo", repo.FullName, "tag", rel.TagName)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func releaseJSON(id, tag string) string {
+	return `{"id":` + id + `,"tag_name":"` + tag + `","name":"Release ` + tag + `","body":"Fixes\n\n* the  thing",` +
+		`"html_url":"https://github.com/octocat/hello-world/releases/tag/` + tag + `"}`
+}
+
+func TestScanReleasesReportsNewTagOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/releases", serveJSON(`[`+releaseJSON("1", "v1.0.0")+`]`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"watchReleases": true,
+		"releaseRepos":  "octocat/hello-world",
+		"releaseNotes":  true,
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/repos/octocat/hello-world/releases", serveJSON(`[`+releaseJSON("2", "v1.1.0")+`,`+
+		`{"id":3,"tag_name":"v2.0.0","draft":true},`+releaseJSON("1", "v1.0.0")+`]`))
+	p.scanReleases(true)
+	p.scanReleases(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "New release of octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "Release v1.1.0 (v1.1.0)\nFixes * the thing", msgs[0].Message)
+	assert.Equal(t, "https://github.com/octocat/hello-world/releases/tag/v1.1.0",
+		msgs[0].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+}
+
+func TestReleaseReposMustNameRepos(t *testing.T) {
+	_, err := parseRepoList("releaseRepos", "octocat/hello-world, octocat/*")
+	assert.ErrorContains(t, err, `releaseRepos entry "octocat/*"`)
+}
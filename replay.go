@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"time"
+)
+
+// fetchReplay returns the notifications that changed while the plugin was
+// disabled, including ones that were already read on GitHub. The window is
+// bounded by replayWindow and the result by replayMaxItems, oldest first.
+func (c *MyPlugin) fetchReplay(since time.Time) []GithubNotification {
+	if !c.readsNotifications() {
+		return nil
+	}
+	if earliest := c.clock.Now().Add(-c.replayWindow); since.Before(earliest) {
+		since = earliest
+	}
+	query := url.Values{
+		"all":   {"true"},
+		"since": {since.UTC().Format(time.RFC3339)},
+	}
+	var notifications []GithubNotification
+	if err := c.getJSON(c.baseURL+"/notifications?"+query.Encode(), "application/vnd.github.v3+json", &notifications); err != nil {
+		c.recordError("fetching notifications for replay", err)
+		return nil
+	}
+	notifications = uniqueThreads(notifications)
+
+	sort.Slice(notifications, func(i, j int) bool {
+		return notifications[i].UpdatedAt.Before(notifications[j].UpdatedAt)
+	})
+	if len(notifications) > c.replayMaxItems {
+		c.infoLog("replaying only the latest missed notifications", "replayed", c.replayMaxItems, "missed", len(notifications))
+		notifications = notifications[len(notifications)-c.replayMaxItems:]
+	}
+	now := c.clock.Now()
+	for _, n := range notifications {
+		c.seenNotifications.mark(n.ID, now)
+	}
+	return notifications
+}
+
+// deliverReplay sends missed notifications, spaced out by replayThrottle so
+// a long downtime does not flood the client. They are classified like new
+// ones, so filtered, muted and suppressed threads stay quiet. It gives up
+// once stop is closed. The caller holds pollMu.
+func (c *MyPlugin) deliverReplay(replay []GithubNotification, stop <-chan struct{}) {
+	filter := c.activeFilter()
+	sent := 0
+	for _, n := range replay {
+		priority, vipActor, skip := c.classifyNotification(n, filter)
+		if skip != "" {
+			c.debugLog("skipping replayed notification", "id", n.ID, "reason", skip)
+			continue
+		}
+		if sent > 0 && c.replayThrottle > 0 {
+			select {
+			case <-time.After(c.replayThrottle):
+			case <-stop:
+				return
+			}
+		}
+		sent++
+		label, _ := notificationLabel(n.Subject.Type)
+		details := []string{c.translate("replay.detail")}
+		if vipActor != "" {
+			details = append(details, "from @"+vipActor)
+		}
+		c.sendPending(pendingNotification{n, label, priority, details, vipActor != ""})
+	}
+}
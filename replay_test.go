@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gotify/plugin-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayDeliversMissedNotificationsOnEnable(t *testing.T) {
+	srv := newFixtureServer(t)
+	var replayQuery []string
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") == "true" {
+			replayQuery = append(replayQuery, r.URL.Query().Get("since"))
+			serveJSON(`[
+				{"id": "3", "repository": {"full_name": "octocat/hello-world"}, "subject": {"title": "Newer", "url": "https://api.github.com/repos/octocat/hello-world/issues/3", "type": "Issue"}, "updated_at": "2024-05-01T11:00:00Z"},
+				{"id": "2", "repository": {"full_name": "octocat/hello-world"}, "subject": {"title": "Older", "url": "https://api.github.com/repos/octocat/hello-world/issues/2", "type": "Issue"}, "updated_at": "2024-05-01T10:00:00Z"},
+				{"id": "1", "repository": {"full_name": "octocat/hello-world"}, "subject": {"title": "Oldest", "url": "https://api.github.com/repos/octocat/hello-world/issues/1", "type": "Issue"}, "updated_at": "2024-05-01T09:00:00Z"}
+			]`)(w, r)
+			return
+		}
+		serveJSON(`[]`)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"replayOnEnable": true, "replayWindow": 2, "replayMaxItems": 2})
+	clk := newFakeClock()
+	p.clock = clk
+	p.replayThrottle = 0
+	storage := &memoryStorage{}
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.store.put("", accountState{LastCheckTime: clk.Now().Add(-6 * time.Hour)}))
+
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	require.Eventually(t, func() bool { return len(rec.Messages()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"2024-05-01T10:00:00Z"}, replayQuery, "since must be clamped to the replay window")
+	msgs := rec.Messages()
+	assert.Equal(t, "[Issue] Older", msgs[0].Title)
+	assert.Equal(t, "[Issue] Newer", msgs[1].Title)
+	assert.Contains(t, msgs[0].Message, "missed while the plugin was offline")
+	assert.True(t, p.seenNotifications.has("3"))
+}
+
+func TestReplayNeedsPreviousState(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.URL.Query().Get("all"), "no replay without a previous check time")
+		serveJSON(`[]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"replayOnEnable": true})
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.Enable())
+	p.Disable()
+	assert.Empty(t, rec.Messages())
+	assert.False(t, p.loadState().LastCheckTime.IsZero(), "Enable records the check time for the next start")
+}
+
+func TestReplayValidation(t *testing.T) {
+	p := NewGotifyPluginInstance(plugin.UserContext{ID: 1}).(*MyPlugin)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "replayWindow": 0}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "replayMaxItems": 0}))
+	assert.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "replayOnEnable": true}))
+}
+
+func TestReplayIsClassifiedLikeNewNotifications(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"repoPriorities":       map[string]int{"octocat/noisy": -1, "octocat/critical": 9},
+		"suppressUnknownTypes": true,
+	})
+	p.replayThrottle = 0
+	var replay []GithubNotification
+	require.NoError(t, json.Unmarshal([]byte(`[`+
+		repoNotificationJSON("1", "octocat/noisy")+`,`+
+		repoNotificationJSON("2", "octocat/critical")+`,`+
+		`{"id":"3","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"T3","type":"Unheard","url":""}}`+
+		`]`), &replay))
+
+	p.pollMu.Lock()
+	p.deliverReplay(replay, make(chan struct{}))
+	p.pollMu.Unlock()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "muted repos and suppressed types are not replayed")
+	assert.Equal(t, "[Issue] T2", msgs[0].Title)
+	assert.Equal(t, 9, msgs[0].Priority, "the repo priority applies")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gotify/plugin-api"
+)
+
+// heldBack counts the notifications of a repo held back by its minimum gap
+// and keeps the highest of their priorities for the catch-up message.
+type heldBack struct {
+	count    int
+	priority int
+}
+
+// allowRepoMessage reports whether a notification for repo, to be sent at
+// priority, may be sent now. Notifications inside the repo's minimum gap are
+// counted so a single catch-up message can be sent once the gap has elapsed.
+func (c *MyPlugin) allowRepoMessage(repo string, priority int) bool {
+	if c.repoMinGap <= 0 {
+		return true
+	}
+	last, ok := c.repoLastSent[repo]
+	if !ok || c.clock.Now().Sub(last) >= c.repoMinGap {
+		return true
+	}
+	held := c.repoSuppressed[repo]
+	c.repoSuppressed[repo] = heldBack{count: held.count + 1, priority: max(held.priority, priority)}
+	return false
+}
+
+func (c *MyPlugin) markRepoSent(repo string) {
+	if c.repoMinGap <= 0 {
+		return
+	}
+	c.repoLastSent[repo] = c.clock.Now()
+}
+
+func (c *MyPlugin) flushRepoCatchUps() {
+	for repo, held := range c.repoSuppressed {
+		if c.clock.Now().Sub(c.repoLastSent[repo]) < c.repoMinGap {
+			continue
+		}
+		msg := &plugin.Message{
+			Title:    c.translate("repogap.title", held.count, repo),
+			Message:  c.translate("repogap.body", held.count, repo),
+			Priority: held.priority,
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": c.webURL(repo),
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(*msg); err != nil {
+			c.recordError(fmt.Sprintf("sending catch-up for repo %s", repo), err)
+			continue
+		}
+		delete(c.repoSuppressed, repo)
+		c.repoLastSent[repo] = c.clock.Now()
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepoMinGapSuppressesAndCatchesUp(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"interval": 60, "repoMinGap": 10, "typePriorities": map[string]interface{}{"PullRequest": 5},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.repoLastSent["octocat/hello-world"] = time.Now()
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages())
+	assert.Equal(t, heldBack{count: 1, priority: 5}, p.repoSuppressed["octocat/hello-world"])
+
+	p.flushRepoCatchUps()
+	assert.Empty(t, rec.Messages(), "catch-up must wait for the gap to elapse")
+
+	p.repoLastSent["octocat/hello-world"] = time.Now().Add(-11 * time.Minute)
+	p.flushRepoCatchUps()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "1 more updates in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, 5, msgs[0].Priority, "the catch-up keeps the priority of what it held back")
+	assert.Empty(t, p.repoSuppressed)
+}
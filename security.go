@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gotify/plugin-api"
+)
+
+type dependabotAlert struct {
+	Number     int    `json:"number"`
+	HTMLURL    string `json:"html_url"`
+	Dependency struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+	} `json:"dependency"`
+	SecurityAdvisory struct {
+		GHSAID   string `json:"ghsa_id"`
+		Summary  string `json:"summary"`
+		Severity string `json:"severity"`
+	} `json:"security_advisory"`
+}
+
+// scanSecurityAlerts reports open Dependabot alerts of every watched repo
+// that were not seen before, keyed by repo and alert number. A token without
+// access to the alerts of a repo is reported once and the repo is skipped
+// until the plugin is re-enabled; so are repos with Dependabot alerts
+// disabled.
+func (c *MyPlugin) scanSecurityAlerts(notify bool) {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for security alert watch", err)
+		return
+	}
+
+	for _, repo := range repos {
+		if c.securityAlertsForbidden[repo.FullName] {
+			continue
+		}
+		endpoint := fmt.Sprintf("%s/repos/%s/dependabot/alerts?state=open&per_page=100", c.baseURL, repo.FullName)
+		alerts, err := fetchAllPages[dependabotAlert](c, endpoint, "application/vnd.github.v3+json")
+		if err != nil {
+			switch classifyError(err).StatusCode {
+			case http.StatusForbidden:
+				c.recordError(fmt.Sprintf("fetching Dependabot alerts of %s (the token needs the security_events scope or Dependabot alerts read access), not checking it again", repo.FullName), err)
+				c.securityAlertsForbidden[repo.FullName] = true
+			case http.StatusNotFound:
+				c.infoLog("skipping security alerts, Dependabot alerts are not enabled", "repo", repo.FullName)
+				c.securityAlertsForbidden[repo.FullName] = true
+			default:
+				c.recordError(fmt.Sprintf("fetching Dependabot alerts of %s", repo.FullName), err)
+			}
+			continue
+		}
+
+		// Alerts are newest first; notify oldest first.
+		for i := len(alerts) - 1; i >= 0; i-- {
+			alert := alerts[i]
+			key := fmt.Sprintf("%s:%d", repo.FullName, alert.Number)
+			if c.seenSecurityAlerts[key] {
+				continue
+			}
+			c.seenSecurityAlerts[key] = true
+			if !notify {
+				continue
+			}
+
+			advisory := alert.SecurityAdvisory
+			msg := &plugin.Message{
+				Title:    fmt.Sprintf("%s severity alert in %s", advisory.Severity, repo.FullName),
+				Message:  fmt.Sprintf("%s (%s): %s", alert.Dependency.Package.Name, alert.Dependency.Package.Ecosystem, advisory.Summary),
+				Priority: 8,
+				Extras: map[string]interface{}{
+					"client::notification": map[string]interface{}{
+						"click": map[string]interface{}{
+							"url": alert.HTMLURL,
+						},
+					},
+				},
+			}
+			if err := c.msgHandler.SendMessage(*msg); err != nil {
+				c.recordError("sending security alert", err)
+			} else {
+				c.infoLog("sent security alert", "ghsa", advisory.GHSAID, "repo", repo.FullName)
+			}
+		}
+	}
+}
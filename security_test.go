@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dependabotAlertJSON(number, pkg string) string {
+	return `{"number":` + number + `,"html_url":"https://github.com/octocat/hello-world/security/dependabot/` + number + `",` +
+		`"dependency":{"package":{"ecosystem":"npm","name":"` + pkg + `"}},` +
+		`"security_advisory":{"ghsa_id":"GHSA-xxxx","summary":"Prototype pollution","severity":"high"}}`
+}
+
+func TestScanSecurityAlertsSendsHighPriorityMessage(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/dependabot/alerts", serveJSON(`[`+dependabotAlertJSON("1", "minimist")+`]`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchSecurityAlerts": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/repos/octocat/hello-world/dependabot/alerts", serveJSON(`[`+
+		dependabotAlertJSON("2", "lodash")+`,`+dependabotAlertJSON("1", "minimist")+`]`))
+	p.scanSecurityAlerts(true)
+	p.scanSecurityAlerts(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "high severity alert in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "lodash (npm): Prototype pollution", msgs[0].Message)
+	assert.Equal(t, 8, msgs[0].Priority)
+}
+
+func TestScanSecurityAlertsReportsMissingScopeOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/dependabot/alerts", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Resource not accessible by personal access token"}`, http.StatusForbidden)
+	})
+
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"watchSecurityAlerts": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	p.scanSecurityAlerts(true)
+
+	var scopeErrors int
+	for _, e := range p.getRecentErrors() {
+		if e.StatusCode == http.StatusForbidden {
+			scopeErrors++
+			assert.Contains(t, e.Op, "security_events")
+		}
+	}
+	assert.Equal(t, 1, scopeErrors)
+}
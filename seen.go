@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// seenSet records when each notification thread or star was last observed
+// on GitHub. Entries that were not observed for seenTTL are evicted, so the
+// sets do not grow for the lifetime of the plugin. Everything still listed
+// by GitHub is observed on every poll and therefore never evicted.
+type seenSet map[string]time.Time
+
+func (s seenSet) has(key string) bool {
+	_, ok := s[key]
+	return ok
+}
+
+// mark records key as seen at now, or refreshes it when it already is.
+func (s seenSet) mark(key string, now time.Time) {
+	s[key] = now
+}
+
+// markPrefix refreshes every entry starting with prefix.
+func (s seenSet) markPrefix(prefix string, now time.Time) {
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			s[key] = now
+		}
+	}
+}
+
+// deletePrefix removes every entry starting with prefix and returns how many
+// were removed.
+func (s seenSet) deletePrefix(prefix string) int {
+	deleted := 0
+	for key := range s {
+		if strings.HasPrefix(key, prefix) {
+			delete(s, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// evict removes the entries last observed before cutoff and returns how many
+// were removed.
+func (s seenSet) evict(cutoff time.Time) int {
+	evicted := 0
+	for key, at := range s {
+		if at.Before(cutoff) {
+			delete(s, key)
+			evicted++
+		}
+	}
+	return evicted
+}
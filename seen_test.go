@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeenNotificationsExpireOnceNoLongerListed(t *testing.T) {
+	srv := newFixtureServer(t)
+	notifications := "[" + notificationJSON("1", "Old thread") + "]"
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(notifications)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"seenTTL": 1})
+	clk := newFakeClock()
+	p.clock = clk
+	p.seenNotifications = seenSet{"1": clk.Now(), "gone": clk.Now()}
+
+	clk.Advance(2 * time.Hour)
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "a listed thread is never evicted")
+	assert.True(t, p.seenNotifications.has("1"))
+	assert.False(t, p.seenNotifications.has("gone"), "threads not listed for the TTL are purged")
+
+	notifications = `[]`
+	clk.Advance(30 * time.Minute)
+	p.checkNotifications()
+	assert.True(t, p.seenNotifications.has("1"), "kept within the TTL")
+	clk.Advance(time.Hour)
+	p.checkNotifications()
+	assert.Empty(t, p.seenNotifications)
+}
+
+func TestSeenStarsOfUnwatchedReposExpire(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"stars"`)
+		if r.Header.Get("If-None-Match") == `"stars"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		serveJSON(`[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "seenTTL": 1})
+	clk := newFakeClock()
+	p.clock = clk
+	p.seenStars = seenSet{}
+	p.unstarCandidates = map[string]bool{}
+	p.stargazerETags = map[string]string{}
+	p.checkStars()
+	require.Len(t, rec.Messages(), 1)
+	p.seenStars.mark("octocat/archived:bob", clk.Now())
+
+	clk.Advance(2 * time.Hour)
+	p.checkStars()
+	assert.Len(t, rec.Messages(), 1, "unchanged lists keep their stars fresh")
+	assert.True(t, p.seenStars.has("octocat/hello-world:alice"))
+	assert.False(t, p.seenStars.has("octocat/archived:bob"))
+}
+
+func TestSeenTTLValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "seenTTL": 0}))
+	assert.Equal(t, 720*time.Hour, p.seenTTL)
+}
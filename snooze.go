@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type snoozeEntry struct {
+	ID    string    `json:"id"`
+	Until time.Time `json:"until"`
+}
+
+func (c *MyPlugin) snooze(id string, d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snoozed == nil {
+		c.snoozed = make(map[string]time.Time)
+	}
+	until := c.clock.Now().Add(d)
+	c.snoozed[id] = until
+	return until
+}
+
+func (c *MyPlugin) isSnoozed(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.snoozed[id]
+	return ok && c.clock.Now().Before(until)
+}
+
+// expireSnoozes drops elapsed snoozes and returns their thread IDs.
+func (c *MyPlugin) expireSnoozes() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var expired []string
+	now := c.clock.Now()
+	for id, until := range c.snoozed {
+		if !now.Before(until) {
+			expired = append(expired, id)
+			delete(c.snoozed, id)
+		}
+	}
+	return expired
+}
+
+func (c *MyPlugin) activeSnoozes() []snoozeEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]snoozeEntry, 0, len(c.snoozed))
+	now := c.clock.Now()
+	for id, until := range c.snoozed {
+		if now.Before(until) {
+			entries = append(entries, snoozeEntry{ID: id, Until: until})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Until.Before(entries[j].Until) })
+	return entries
+}
+
+func (c *MyPlugin) handleSnooze(ctx *gin.Context) {
+	id := ctx.Query("id")
+	if id == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		return
+	}
+	minutes, err := strconv.Atoi(ctx.Query("minutes"))
+	if err != nil || minutes <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "minutes must be a positive integer"})
+		return
+	}
+	until := c.snooze(id, time.Duration(minutes)*time.Minute)
+	ctx.JSON(http.StatusOK, snoozeEntry{ID: id, Until: until})
+}
+
+func (c *MyPlugin) handleListSnoozes(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.activeSnoozes())
+}
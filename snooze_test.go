@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebhookRouter(p *MyPlugin) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	p.RegisterWebhook("/plugin/1/custom/test", r.Group("/"))
+	return r
+}
+
+func TestSnoozeWebhook(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret"})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	r := newWebhookRouter(p)
+	snooze := func(query, secret string) int {
+		req := httptest.NewRequest(http.MethodPost, "/snooze?"+query, nil)
+		req.Header.Set("X-Webhook-Secret", secret)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, snooze("id=2&minutes=30", "wrong"))
+	require.Equal(t, http.StatusOK, snooze("id=2&minutes=30", "s3cret"))
+	assert.Equal(t, http.StatusBadRequest, snooze("id=2&minutes=0", "s3cret"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/snooze", nil))
+	var entries []snoozeEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "2", entries[0].ID)
+
+	srv.serveFixture("/notifications", "notifications.json")
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "the snoozed thread must not notify")
+	assert.Equal(t, "[Issue] Add README", msgs[0].Title)
+
+	p.snooze("2", -time.Second)
+	p.checkNotifications()
+	msgs = rec.Messages()
+	require.Len(t, msgs, 2, "the thread notifies again once the snooze expires")
+	assert.Equal(t, "[PR] Fix the thing", msgs[1].Title)
+}
+
+func TestSnoozeRenotify(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications.json")
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"snoozeRenotify": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.snooze("2", -time.Second)
+	p.checkNotifications()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "[PR] Fix the thing", msgs[0].Title)
+}
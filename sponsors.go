@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gotify/plugin-api"
+)
+
+const sponsorshipsQuery = `query($after: String) {
+  viewer {
+    hasSponsorsListing
+    sponsorshipsAsMaintainer(first: 100, after: $after, includePrivate: true) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id
+        isOneTimePayment
+        tier { name monthlyPriceInDollars }
+        sponsorEntity {
+          ... on User { login }
+          ... on Organization { login }
+        }
+      }
+    }
+  }
+}`
+
+// sponsorRecord is the persisted view of one active sponsorship.
+type sponsorRecord struct {
+	Login string `json:"login"`
+	Tier  string `json:"tier"`
+}
+
+type sponsorship struct {
+	ID               string `json:"id"`
+	IsOneTimePayment bool   `json:"isOneTimePayment"`
+	Tier             *struct {
+		Name                  string `json:"name"`
+		MonthlyPriceInDollars int    `json:"monthlyPriceInDollars"`
+	} `json:"tier"`
+	SponsorEntity struct {
+		Login string `json:"login"`
+	} `json:"sponsorEntity"`
+}
+
+func (s sponsorship) tierName() string {
+	if s.Tier == nil {
+		return "custom amount"
+	}
+	return s.Tier.Name
+}
+
+// fetchSponsorships returns the active sponsorships of the token's account.
+// ok is false when the account has no GitHub Sponsors profile.
+func (c *MyPlugin) fetchSponsorships() (list []sponsorship, ok bool, err error) {
+	var after interface{}
+	for page := 0; page < c.maxPages; page++ {
+		var data struct {
+			Viewer struct {
+				HasSponsorsListing       bool `json:"hasSponsorsListing"`
+				SponsorshipsAsMaintainer struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []sponsorship `json:"nodes"`
+				} `json:"sponsorshipsAsMaintainer"`
+			} `json:"viewer"`
+		}
+		if err := c.graphQL(sponsorshipsQuery, map[string]interface{}{"after": after}, &data); err != nil {
+			return nil, false, err
+		}
+		if !data.Viewer.HasSponsorsListing {
+			return nil, false, nil
+		}
+		conn := data.Viewer.SponsorshipsAsMaintainer
+		list = append(list, conn.Nodes...)
+		if !conn.PageInfo.HasNextPage {
+			return list, true, nil
+		}
+		after = conn.PageInfo.EndCursor
+	}
+	c.warnTruncated("sponsorshipsAsMaintainer")
+	return list, true, nil
+}
+
+// checkSponsors compares the active sponsorships with the persisted ones and
+// reports new sponsors, tier changes and cancellations, keyed by sponsorship
+// node ID. The first check only records the current sponsors. Accounts
+// without a Sponsors profile are skipped until the plugin is re-enabled.
+func (c *MyPlugin) checkSponsors() {
+	if c.sponsorsUnavailable {
+		return
+	}
+	list, ok, err := c.fetchSponsorships()
+	if err != nil {
+		c.recordError("fetching sponsorships (the token needs the read:user scope)", err)
+		return
+	}
+	if !ok {
+		c.infoLog("GitHub Sponsors is not set up for this account, not watching sponsorships")
+		c.sponsorsUnavailable = true
+		return
+	}
+
+	current := make(map[string]sponsorRecord, len(list))
+	for _, s := range list {
+		current[s.ID] = sponsorRecord{Login: s.SponsorEntity.Login, Tier: s.tierName()}
+	}
+	previous := c.sponsors
+	c.sponsors = current
+	defer c.saveState()
+	if previous == nil {
+		return
+	}
+
+	for _, s := range list {
+		record := current[s.ID]
+		old, existed := previous[s.ID]
+		switch {
+		case !existed && s.IsOneTimePayment:
+			c.sendSponsorMessage(fmt.Sprintf("💖 One-time sponsorship from %s", record.Login),
+				fmt.Sprintf("%s sponsored you (%s). Thank you!", record.Login, record.Tier), 5)
+		case !existed:
+			c.sendSponsorMessage(fmt.Sprintf("💖 New sponsor: %s", record.Login),
+				fmt.Sprintf("%s is now sponsoring you (%s).", record.Login, record.Tier), 5)
+		case old.Tier != record.Tier:
+			c.sendSponsorMessage(fmt.Sprintf("Sponsor %s changed tier", record.Login),
+				fmt.Sprintf("%s moved from %s to %s.", record.Login, old.Tier, record.Tier), 3)
+		}
+	}
+	var ended []string
+	for id := range previous {
+		if _, ok := current[id]; !ok {
+			ended = append(ended, id)
+		}
+	}
+	sort.Strings(ended)
+	for _, id := range ended {
+		old := previous[id]
+		c.sendSponsorMessage(fmt.Sprintf("Sponsorship from %s ended", old.Login),
+			fmt.Sprintf("%s is no longer sponsoring you (%s).", old.Login, old.Tier), 2)
+	}
+}
+
+func (c *MyPlugin) sendSponsorMessage(title, message string, priority int) {
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  message,
+		Priority: priority,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": c.webURL("sponsors/dashboard"),
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending sponsor notification", err)
+	} else {
+		c.infoLog("sent sponsor notification", "title", title)
+	}
+}
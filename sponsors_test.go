@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sponsorsResponse(nodes string) http.HandlerFunc {
+	return serveJSON(`{"data":{"viewer":{"hasSponsorsListing":true,"sponsorshipsAsMaintainer":{"pageInfo":{"hasNextPage":false},"nodes":[` + nodes + `]}}}}`)
+}
+
+func TestSponsorChanges(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/graphql", sponsorsResponse(`
+		{"id":"S_1","tier":{"name":"$5 a month","monthlyPriceInDollars":5},"sponsorEntity":{"login":"alice"}},
+		{"id":"S_2","tier":{"name":"$5 a month","monthlyPriceInDollars":5},"sponsorEntity":{"login":"bob"}}`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchSponsors": true})
+	p.SetStorageHandler(&memoryStorage{})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, rec.Messages(), "the first check only records the sponsors")
+
+	srv.handle("/graphql", sponsorsResponse(`
+		{"id":"S_1","tier":{"name":"$25 a month","monthlyPriceInDollars":25},"sponsorEntity":{"login":"alice"}},
+		{"id":"S_3","isOneTimePayment":true,"tier":null,"sponsorEntity":{"login":"acme"}}`))
+	p.checkSponsors()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 3)
+	assert.Equal(t, "Sponsor alice changed tier", msgs[0].Title)
+	assert.Equal(t, "alice moved from $5 a month to $25 a month.", msgs[0].Message)
+	assert.Equal(t, "💖 One-time sponsorship from acme", msgs[1].Title)
+	assert.Equal(t, "Sponsorship from bob ended", msgs[2].Title)
+
+	assert.Len(t, p.loadState().Sponsors, 2, "sponsors are persisted")
+	p.checkSponsors()
+	assert.Len(t, rec.Messages(), 3)
+}
+
+func TestSponsorsDisabledAccount(t *testing.T) {
+	srv := newFixtureServer(t)
+	requests := 0
+	srv.handle("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		serveJSON(`{"data":{"viewer":{"hasSponsorsListing":false,"sponsorshipsAsMaintainer":{"nodes":[]}}}}`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchSponsors": true})
+
+	p.checkSponsors()
+	p.checkSponsors()
+	assert.Equal(t, 1, requests)
+	assert.Empty(t, rec.Messages())
+}
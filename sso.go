@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+// ssoAuthorizationURL returns the authorization link from an
+// "X-GitHub-SSO: required; url=..." header, or "" if the header is absent or
+// only reports partial results.
+func ssoAuthorizationURL(h http.Header) string {
+	value := h.Get("X-GitHub-SSO")
+	parts := strings.Split(value, ";")
+	if strings.TrimSpace(parts[0]) != "required" {
+		return ""
+	}
+	for _, part := range parts[1:] {
+		if u, ok := strings.CutPrefix(strings.TrimSpace(part), "url="); ok {
+			return u
+		}
+	}
+	return ""
+}
+
+// ssoOrganization extracts the organization from an authorization link like
+// https://github.com/orgs/acme/sso?authorization_request=...
+func ssoOrganization(authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "orgs" {
+		return parts[1]
+	}
+	return ""
+}
+
+// checkSSO tells the user once per organization when GitHub refuses access
+// because the token is not authorized for the organization's SAML SSO.
+// Without this the organization's notifications just silently go missing.
+func (c *MyPlugin) checkSSO(resp *http.Response) {
+	authURL := ssoAuthorizationURL(resp.Header)
+	if authURL == "" {
+		return
+	}
+	org := ssoOrganization(authURL)
+	key := org
+	if key == "" {
+		key = authURL
+	}
+
+	c.mu.Lock()
+	if c.ssoAlerted[key] {
+		c.mu.Unlock()
+		return
+	}
+	if c.ssoAlerted == nil {
+		c.ssoAlerted = make(map[string]bool)
+	}
+	c.ssoAlerted[key] = true
+	c.mu.Unlock()
+
+	name := org
+	if name == "" {
+		name = "an organization"
+	}
+	c.infoLog("token is not authorized for SAML SSO", "org", name, "authorize_url", authURL)
+	if c.msgHandler == nil {
+		return
+	}
+	msg := plugin.Message{
+		Title:    fmt.Sprintf("GitHub token needs SSO authorization for %s", name),
+		Message:  fmt.Sprintf("Notifications from %s are not delivered until the token is authorized for its SAML single sign-on. Authorize it here: %s", name, authURL),
+		Priority: 8,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": authURL,
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(msg); err != nil {
+		c.recordError("sending SSO alert", err)
+	}
+}
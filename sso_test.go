@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSOAuthorizationURL(t *testing.T) {
+	h := http.Header{}
+	assert.Equal(t, "", ssoAuthorizationURL(h))
+	h.Set("X-GitHub-SSO", "partial-results; organizations=21955855,20582480")
+	assert.Equal(t, "", ssoAuthorizationURL(h))
+	h.Set("X-GitHub-SSO", "required; url=https://github.com/orgs/acme/sso?authorization_request=abc")
+	assert.Equal(t, "https://github.com/orgs/acme/sso?authorization_request=abc", ssoAuthorizationURL(h))
+	assert.Equal(t, "acme", ssoOrganization(ssoAuthorizationURL(h)))
+}
+
+func TestSSORequiredIsReportedOnce(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-SSO", "required; url=https://github.com/orgs/acme/sso?authorization_request=abc")
+		http.Error(w, `{"message":"Resource protected by organization SAML enforcement."}`, http.StatusForbidden)
+	})
+	p, rec := newTestPlugin(t, srv, nil)
+	p.seenNotifications = seenSet{}
+
+	p.checkNotifications()
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "no generic API error alert and no repeats")
+	assert.Equal(t, "GitHub token needs SSO authorization for acme", msgs[0].Title)
+	assert.Contains(t, msgs[0].Message, "https://github.com/orgs/acme/sso?authorization_request=abc")
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.False(t, p.isPaused(), "other organizations keep being polled")
+	assert.Equal(t, errorKindSSO, p.lastError.Kind)
+}
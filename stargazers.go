@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stargazer is an entry of the stargazer list in the star+json media type,
+// which adds when the star was given.
+type stargazer struct {
+	StarredAt time.Time `json:"starred_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// stargazerList is the result of fetchStargazers.
+type stargazerList struct {
+	stars []stargazer
+	// changed is false when GitHub reported the list unmodified.
+	changed bool
+	// paginated lists were read with several requests, so they may have
+	// shifted in between; truncated ones were cut short by maxPages.
+	paginated bool
+	truncated bool
+	// etag is the ETag of a single-page list.
+	etag string
+}
+
+// starKey identifies a star in seenStars by repo and the stargazer's login.
+func starKey(repo, login string) string {
+	return starKeyPrefix(repo) + login
+}
+
+// starKeyPrefix is what the keys of every star of repo start with.
+func starKeyPrefix(repo string) string {
+	return repo + ":"
+}
+
+// stargazerResult is the outcome of fetching the stargazers of one repo.
+type stargazerResult struct {
+	repo string
+	list stargazerList
+	err  error
+}
+
+// starWatchedRepos returns the repos whose stars are polled: the configured
+// starRepos, or every watched repo when none are configured.
+func (c *MyPlugin) starWatchedRepos() ([]Repo, error) {
+	if len(c.starRepos) == 0 {
+		return c.watchedRepos()
+	}
+	repos := make([]Repo, len(c.starRepos))
+	for i, repo := range c.starRepos {
+		repos[i] = Repo{FullName: repo}
+	}
+	return repos, nil
+}
+
+// fetchStargazersOf fetches the stargazers of repos with up to starWorkers
+// requests in flight, returning the results in the order of repos. All
+// workers share the rate limit window of do, so they hold off together when
+// the quota runs low. Once a fetch fails in a way that stops polling, the
+// repos not yet started are skipped and carry that error.
+func (c *MyPlugin) fetchStargazersOf(repos []Repo) []stargazerResult {
+	// The workers do not own pollMu, so none of them may let go of it
+	// while waiting out the rate limit.
+	defer c.setPolling(c.setPolling(false))
+
+	results := make([]stargazerResult, len(repos))
+	jobs := make(chan int)
+	var (
+		wg       sync.WaitGroup
+		abortMu  sync.Mutex
+		abortErr error
+	)
+	for w := 0; w < min(c.starWorkers, len(repos)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				repo := repos[i].FullName
+				abortMu.Lock()
+				err := abortErr
+				abortMu.Unlock()
+				if err != nil {
+					results[i] = stargazerResult{repo: repo, err: err}
+					continue
+				}
+				list, err := c.fetchStargazers(repo)
+				results[i] = stargazerResult{repo: repo, list: list, err: err}
+				if err != nil && stopsStarFetches(err) {
+					abortMu.Lock()
+					if abortErr == nil {
+						abortErr = err
+					}
+					abortMu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range repos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// stopsStarFetches reports whether err means no further stargazers should be
+// fetched in this poll: the token was rejected, the rate limit is exhausted
+// or Disable aborted the request.
+func stopsStarFetches(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return true
+	}
+	switch classifyError(err).Kind {
+	case errorKindAuth, errorKindRateLimit:
+		return true
+	}
+	return false
+}
+
+// isNotFound reports a 404, which GitHub returns for the stargazers of a
+// repo that was deleted or that the token lost access to.
+func isNotFound(err error) bool {
+	return err != nil && classifyError(err).StatusCode == http.StatusNotFound
+}
+
+// forgetStarsOf drops the seen stars of a repo that no longer exists, so
+// they are not kept until they expire. It reports whether any were dropped.
+func (c *MyPlugin) forgetStarsOf(repo string) bool {
+	prefix := starKeyPrefix(repo)
+	for key := range c.unstarCandidates {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.unstarCandidates, key)
+		}
+	}
+	delete(c.stargazerETags, repo)
+	n := c.seenStars.deletePrefix(prefix)
+	if n == 0 {
+		c.debugLog("skipping stars", "repo", repo, "reason", "not found")
+		return false
+	}
+	c.infoLog("repo was not found, forgot its stars", "repo", repo, "count", n)
+	return true
+}
+
+// rememberStargazerETag keeps the ETag of a single-page stargazer list for
+// the next conditional request. It runs after the workers are done, so they
+// only ever read stargazerETags.
+func (c *MyPlugin) rememberStargazerETag(result stargazerResult) {
+	switch {
+	case result.err != nil || !result.list.changed:
+	case result.list.paginated:
+		delete(c.stargazerETags, result.repo)
+	default:
+		c.stargazerETags[result.repo] = result.list.etag
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStargazerWorkersCheckEveryRepoWithinTheCap(t *testing.T) {
+	const repoCount = 20
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	repos := make([]string, repoCount)
+	for i := range repos {
+		repos[i] = fmt.Sprintf(`{"full_name":"octocat/repo-%d"}`, i)
+	}
+	srv.handle("/user/repos", serveJSON("["+strings.Join(repos, ",")+"]"))
+
+	var (
+		mu              sync.Mutex
+		inFlight, maxIn int
+		fetched         = map[string]int{}
+		polling         bool
+	)
+	stargazers := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxIn = max(maxIn, inFlight)
+		fetched[r.URL.Path]++
+		stars := `[]`
+		if polling {
+			stars = `[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"alice"}}]`
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		serveJSON(stars)(w, r)
+	}
+	for i := range repoCount {
+		srv.handle(fmt.Sprintf("/repos/octocat/repo-%d/stargazers", i), stargazers)
+	}
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "starWorkers": 3})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	mu.Lock()
+	polling = true
+	fetched = map[string]int{}
+	maxIn = 0
+	mu.Unlock()
+	p.checkStars()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, fetched, repoCount, "every repo is checked")
+	assert.LessOrEqual(t, maxIn, 3)
+	assert.Greater(t, maxIn, 1, "repos are fetched in parallel")
+	msgs := rec.Messages()
+	require.Len(t, msgs, repoCount)
+	assert.Equal(t, "Repo octocat/repo-0 received a star from alice", msgs[0].Message, "results keep the repo order")
+	assert.Equal(t, fmt.Sprintf("Repo octocat/repo-%d received a star from alice", repoCount-1), msgs[repoCount-1].Message)
+}
+
+func TestStarWorkersValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Equal(t, 4, p.starWorkers)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "starWorkers": 0}))
+}
+
+func TestStarReposSkipRepoListing(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the repo list must not be fetched when starRepos is set")
+		serveJSON(`[]`)(w, r)
+	})
+	stars := `[]`
+	srv.handle("/repos/someone-else/project/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(stars)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "starRepos": " someone-else/project "})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	stars = `[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"alice"}}]`
+	p.checkStars()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Repo someone-else/project received a star from alice", msgs[0].Message)
+}
+
+func TestStarReposFallBackToAllRepos(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"},{"full_name":"octocat/spoon-knife"}]`))
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	repos, err := p.starWatchedRepos()
+	require.NoError(t, err)
+	assert.Equal(t, []Repo{{FullName: "octocat/hello-world"}, {FullName: "octocat/spoon-knife"}}, repos)
+}
+
+func TestStarReposValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	for _, invalid := range []string{"octocat", "octocat/", "/hello-world", "octocat/hello/world", "octocat/*"} {
+		assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "starRepos": invalid}), invalid)
+	}
+	require.NoError(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "starRepos": "octocat/hello-world, octocat/Spoon-Knife"}))
+	assert.Equal(t, []string{"octocat/hello-world", "octocat/Spoon-Knife"}, p.starRepos)
+}
+
+func TestStarKey(t *testing.T) {
+	assert.Equal(t, "octocat/hello-world:alice", starKey("octocat/hello-world", "alice"))
+	assert.True(t, strings.HasPrefix(starKey("octocat/hello-world", "alice"), starKeyPrefix("octocat/hello-world")))
+	assert.False(t, strings.HasPrefix(starKey("octocat/hello-world-2", "alice"), starKeyPrefix("octocat/hello-world")),
+		"a repo whose name extends another's does not share its prefix")
+}
+
+func TestStargazerDecoding(t *testing.T) {
+	var stars []stargazer
+	require.NoError(t, json.Unmarshal([]byte(`[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice","id":1}}]`), &stars))
+	require.Len(t, stars, 1)
+	assert.Equal(t, "alice", stars[0].User.Login)
+	assert.Equal(t, time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), stars[0].StarredAt)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+// accountState is the part of the plugin state that survives restarts.
+type accountState struct {
+	LastCheckTime time.Time `json:"lastCheckTime"`
+	// Collaborators maps repo to login to role.
+	Collaborators map[string]map[string]string `json:"collaborators,omitempty"`
+	// Sponsors maps sponsorship node ID to sponsor. An empty map means the
+	// sponsors were checked and there are none.
+	Sponsors map[string]sponsorRecord `json:"sponsors"`
+	// BranchHeads maps repo to the last seen head of its default branch.
+	BranchHeads map[string]string `json:"branchHeads,omitempty"`
+	// LastTrafficDigest is the local date the traffic digest was last sent.
+	LastTrafficDigest string `json:"lastTrafficDigest,omitempty"`
+	// Filter is set through POST /filters and replaces the configured
+	// filters until it is deleted again.
+	Filter *notificationFilter `json:"filter,omitempty"`
+	// SeenNotifications and SeenStars are the threads and stars already
+	// handled. Nil means nothing was saved; an empty list means nothing was
+	// seen.
+	SeenNotifications []string `json:"seenNotifications"`
+	SeenStars         []string `json:"seenStars"`
+	// QuietQueue is what quiet hours held back when the plugin was disabled.
+	QuietQueue []heldMessage `json:"quietQueue,omitempty"`
+}
+
+// stateStore keeps the persisted state of the main account and every extra
+// account in the single storage slot Gotify provides, keyed by label.
+type stateStore struct {
+	mu      sync.Mutex
+	handler plugin.StorageHandler
+	// recordError, if set, is told about state that had to be discarded.
+	recordError func(op string, err error)
+}
+
+func (s *stateStore) load() (map[string]*accountState, error) {
+	b, err := s.handler.Load()
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]*accountState)
+	if len(b) == 0 {
+		return states, nil
+	}
+	var stored struct {
+		Accounts map[string]*accountState `json:"accounts"`
+	}
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	for key, state := range stored.Accounts {
+		if state != nil {
+			states[key] = state
+		}
+	}
+	return states, nil
+}
+
+func (s *stateStore) get(key string) (accountState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.load()
+	if err != nil {
+		return accountState{}, err
+	}
+	if state, ok := states[key]; ok {
+		return *state, nil
+	}
+	return accountState{}, nil
+}
+
+func (s *stateStore) put(key string, state accountState) error {
+	return s.update(key, func(stored *accountState) { *stored = state })
+}
+
+// update applies fn to the stored state of key and saves the result, so
+// independent parts of the state can be written without clobbering each
+// other.
+func (s *stateStore) update(key string, fn func(*accountState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states, err := s.load()
+	if err != nil {
+		// Overwrite a corrupt blob rather than never saving again.
+		if s.recordError != nil {
+			s.recordError("discarding unreadable plugin state", err)
+		}
+		states = make(map[string]*accountState)
+	}
+	state, ok := states[key]
+	if !ok {
+		state = &accountState{}
+		states[key] = state
+	}
+	fn(state)
+	b, err := json.Marshal(map[string]interface{}{"accounts": states})
+	if err != nil {
+		return err
+	}
+	return s.handler.Save(b)
+}
+
+func (c *MyPlugin) SetStorageHandler(h plugin.StorageHandler) {
+	c.store = &stateStore{handler: h, recordError: func(op string, err error) { c.recordError(op, err) }}
+	c.applyMessageHandler()
+}
+
+func (c *MyPlugin) loadState() accountState {
+	if c.store == nil {
+		return accountState{}
+	}
+	state, err := c.store.get(c.storageKey)
+	if err != nil {
+		c.recordError("loading plugin state", err)
+		return accountState{}
+	}
+	return state
+}
+
+func (c *MyPlugin) saveState() {
+	if c.store == nil {
+		return
+	}
+	err := c.store.update(c.storageKey, func(state *accountState) {
+		state.LastCheckTime = c.lastCheckTime
+		state.Collaborators = nil
+		if c.watchCollaborators {
+			state.Collaborators = c.collaborators
+		}
+		state.Sponsors = nil
+		if c.watchSponsors {
+			state.Sponsors = c.sponsors
+		}
+		state.BranchHeads = nil
+		if c.watchForcePushes {
+			state.BranchHeads = c.branchHeads
+		}
+		state.LastTrafficDigest = c.lastTrafficDigest
+		state.SeenNotifications = sortedKeys(c.seenNotifications)
+		state.SeenStars = nil
+		if c.watchStars {
+			state.SeenStars = sortedKeys(c.seenStars)
+		}
+		state.QuietQueue = c.quietHandler.snapshot()
+	})
+	if err != nil {
+		c.recordError("saving plugin state", err)
+	}
+}
+
+// restoreSeen replaces what fetchInitialState marked as seen with the state
+// saved before the plugin was last disabled, so the first poll reports what
+// arrived in between. Without saved state the initial fetch stands.
+func (c *MyPlugin) restoreSeen(previous accountState) {
+	now := c.clock.Now()
+	if previous.SeenNotifications != nil {
+		c.seenNotifications = make(seenSet, len(previous.SeenNotifications))
+		for _, id := range previous.SeenNotifications {
+			c.seenNotifications.mark(id, now)
+		}
+	}
+	if c.watchStars && previous.SeenStars != nil {
+		c.seenStars = make(seenSet, len(previous.SeenStars))
+		for _, key := range previous.SeenStars {
+			c.seenStars.mark(key, now)
+		}
+		// The initial fetch stored the current ETags, which would hide the
+		// stars that arrived in between from the first poll.
+		c.stargazerETags = make(map[string]string)
+	}
+}
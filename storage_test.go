@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStorage struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (s *memoryStorage) Save(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append([]byte(nil), b...)
+	return nil
+}
+
+func (s *memoryStorage) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]byte(nil), s.data...), nil
+}
+
+func TestStateStoreKeepsAccountsApart(t *testing.T) {
+	store := &stateStore{handler: &memoryStorage{}}
+	main := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	work := main.Add(time.Hour)
+
+	require.NoError(t, store.put("", accountState{LastCheckTime: main}))
+	require.NoError(t, store.put("work", accountState{LastCheckTime: work}))
+
+	got, err := store.get("")
+	require.NoError(t, err)
+	assert.True(t, main.Equal(got.LastCheckTime))
+	got, err = store.get("work")
+	require.NoError(t, err)
+	assert.True(t, work.Equal(got.LastCheckTime))
+	got, err = store.get("missing")
+	require.NoError(t, err)
+	assert.True(t, got.LastCheckTime.IsZero())
+}
+
+func TestCheckNotificationsPersistsLastCheckTime(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	p, _ := newTestPlugin(t, srv, nil)
+	clk := newFakeClock()
+	p.clock = clk
+	p.SetStorageHandler(&memoryStorage{})
+	p.seenNotifications = seenSet{}
+
+	p.checkNotifications()
+	assert.True(t, clk.Now().Equal(p.loadState().LastCheckTime))
+}
+
+func TestSeenStateSurvivesRestart(t *testing.T) {
+	srv := newFixtureServer(t)
+	notifications := "[" + notificationJSON("1", "Before") + "]"
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(notifications)(w, r)
+	})
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	stars := `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(stars)(w, r)
+	})
+	storage := &memoryStorage{}
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.Enable())
+	require.NoError(t, p.Disable())
+	assert.Empty(t, rec.Messages())
+
+	// Both arrive while the plugin is down.
+	notifications = "[" + notificationJSON("2", "While down") + "," + notificationJSON("1", "Before") + "]"
+	stars = `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}},` +
+		`{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"bob"}}]`
+
+	p, rec = newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	p.checkNotifications()
+	p.checkStars()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "[Issue] While down", msgs[0].Title)
+	assert.Equal(t, "Repo octocat/hello-world received a star from bob", msgs[1].Message)
+}
+
+func TestCorruptStateFallsBackToInitialFetch(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON("["+notificationJSON("1", "Unread")+"]"))
+	p, rec := newTestPlugin(t, srv, nil)
+	p.SetStorageHandler(&memoryStorage{data: []byte("{not json")})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.checkNotifications()
+	assert.Empty(t, rec.Messages(), "without saved state the current notifications count as seen")
+	assert.Equal(t, []string{"1"}, p.loadState().SeenNotifications, "the corrupt state is replaced")
+	var ops []string
+	for _, e := range p.getRecentErrors() {
+		ops = append(ops, e.Op)
+	}
+	assert.Contains(t, ops, "discarding unreadable plugin state")
+}
+
+func TestRestartOnlyFetchesNotificationsSinceLastCheck(t *testing.T) {
+	srv := newFixtureServer(t)
+	var mu sync.Mutex
+	var since []string
+	list := "[" + notificationJSON("1", "Before") + "]"
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		since = append(since, r.URL.Query().Get("since"))
+		body := list
+		mu.Unlock()
+		serveJSON(body)(w, r)
+	})
+	storage := &memoryStorage{}
+	clk := newFakeClock()
+
+	p, _ := newTestPlugin(t, srv, nil)
+	p.clock = clk
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.Enable())
+	require.NoError(t, p.Disable())
+	stored := p.loadState().LastCheckTime
+
+	clk.Advance(time.Hour)
+	mu.Lock()
+	list = "[" + notificationJSON("2", "While away") + "," + notificationJSON("1", "Before") + "]"
+	mu.Unlock()
+	p, rec := newTestPlugin(t, srv, nil)
+	p.clock = clk
+	p.SetStorageHandler(storage)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	mu.Lock()
+	assert.Len(t, since, 1, "the restart reads no notifications before the first poll")
+	mu.Unlock()
+	p.checkNotifications()
+	p.checkNotifications()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, since, 3)
+	assert.Equal(t, "", since[0], "the first start reads everything")
+	assert.Equal(t, stored.UTC().Format(time.RFC3339), since[1], "the first poll after the restart")
+	assert.Equal(t, "", since[2], "later polls read the complete list")
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "only the thread from while the plugin was down is new")
+	assert.Equal(t, "[Issue] While away", msgs[0].Title)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// templateData is what titleTemplate and messageTemplate can refer to when
+// they use text/template syntax, e.g. "{{.Repo}}: {{.Title}}".
+type templateData struct {
+	Type   string
+	Title  string
+	Repo   string
+	Number string
+	URL    string
+	Reason string
+}
+
+// parseTemplate parses a text/template and executes it once against sample
+// data, so unknown fields are reported by ValidateAndSetConfig instead of on
+// the first notification.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := tmpl.Execute(io.Discard, templateData{}); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// isGoTemplate tells a text/template titleTemplate from one using the
+// {placeholder} syntax.
+func isGoTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+func (c *MyPlugin) templateData(n GithubNotification, typeLabel, number string) templateData {
+	return templateData{
+		Type:   typeLabel,
+		Title:  n.Subject.Title,
+		Repo:   n.Repository.FullName,
+		Number: number,
+		URL:    c.subjectWebURL(n),
+		Reason: n.Reason,
+	}
+}
+
+// render executes tmpl, returning fallback if that fails.
+func (c *MyPlugin) render(tmpl *template.Template, data templateData, fallback string) string {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		c.recordError("rendering "+tmpl.Name(), err)
+		return fallback
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesRenderNotifications(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{
+		"titleSource":     "custom",
+		"titleTemplate":   "{{.Repo}}{{if .Number}} #{{.Number}}{{end}}",
+		"messageTemplate": "{{.Type}} ({{.Reason}}): {{.Title}}\n{{.URL}}",
+	})
+	pr := GithubNotification{Reason: "review_requested"}
+	pr.Repository.FullName = "octocat/hello-world"
+	pr.Subject.Title = "Fix the thing"
+	pr.Subject.Type = "PullRequest"
+	pr.Subject.URL = srv.URL + "/repos/octocat/hello-world/pulls/42"
+
+	title, message := p.formatNotification(pr, "PR")
+	assert.Equal(t, "octocat/hello-world #42", title)
+	assert.Equal(t, "PR (review_requested): Fix the thing\n"+srv.URL+"/octocat/hello-world/pull/42", message)
+
+	release := pr
+	release.Subject.Type = "Release"
+	title, _ = p.formatNotification(release, "Release")
+	assert.Equal(t, "octocat/hello-world", title)
+}
+
+func TestPlaceholderTitleTemplateStillWorks(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"titleSource": "custom", "titleTemplate": "{type}: {title}"})
+	assert.Nil(t, p.titleTmpl)
+	n := GithubNotification{}
+	n.Subject.Title = "v1.0.0"
+	n.Subject.Type = "Release"
+	title, message := p.formatNotification(n, "Release")
+	assert.Equal(t, "Release: v1.0.0", title)
+	assert.Equal(t, "[Release] v1.0.0 in ", message, "no messageTemplate keeps the built-in message")
+}
+
+func TestMalformedTemplatesAreRejected(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	for _, conf := range []map[string]interface{}{
+		{"token": "x", "messageTemplate": "{{.Title"},
+		{"token": "x", "messageTemplate": "{{.Assignee}}"},
+		{"token": "x", "titleSource": "custom", "titleTemplate": "{{if .Repo}}"},
+	} {
+		err := p.ValidateAndSetConfig(conf)
+		require.Error(t, err, conf)
+	}
+	assert.ErrorContains(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "messageTemplate": "{{.Title"}), "messageTemplate")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+// threadRef identifies an issue or pull request pinned with watchThreads.
+type threadRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (r threadRef) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// parseThreadURL accepts web URLs like https://github.com/o/r/pull/42 (also
+// on GitHub Enterprise hosts), API URLs like .../repos/o/r/issues/42 and the
+// short form o/r#42.
+func parseThreadURL(s string) (threadRef, error) {
+	if repo, number, ok := strings.Cut(s, "#"); ok && !strings.Contains(s, "://") {
+		owner, name, ok := strings.Cut(repo, "/")
+		n, err := strconv.Atoi(number)
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") || err != nil || n < 1 {
+			return threadRef{}, fmt.Errorf("invalid thread %q", s)
+		}
+		return threadRef{Owner: owner, Repo: name, Number: n}, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return threadRef{}, fmt.Errorf("invalid thread URL %q", s)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, part := range parts {
+		if part == "repos" {
+			parts = parts[i+1:]
+			break
+		}
+	}
+	if len(parts) < 4 {
+		return threadRef{}, fmt.Errorf("invalid thread URL %q", s)
+	}
+	switch parts[2] {
+	case "issues", "pull", "pulls":
+	default:
+		return threadRef{}, fmt.Errorf("thread URL %q does not point to an issue or pull request", s)
+	}
+	n, err := strconv.Atoi(parts[3])
+	if err != nil || n < 1 {
+		return threadRef{}, fmt.Errorf("invalid thread URL %q", s)
+	}
+	return threadRef{Owner: parts[0], Repo: parts[1], Number: n}, nil
+}
+
+// threadState is what the plugin remembers about a watched thread between
+// polls.
+type threadState struct {
+	State     string
+	Since     time.Time
+	CheckedAt time.Time
+}
+
+type threadIssue struct {
+	Title       string `json:"title"`
+	State       string `json:"state"`
+	HTMLURL     string `json:"html_url"`
+	PullRequest *struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+type threadComment struct {
+	ID        int64     `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	User      struct {
+		Login string `json:"login"`
+	} `json:"user"`
+}
+
+// threadStateLabel returns "open", "closed" or "merged".
+func (i threadIssue) threadStateLabel() string {
+	if i.PullRequest != nil && i.PullRequest.MergedAt != nil {
+		return "merged"
+	}
+	return i.State
+}
+
+// scanWatchedThreads polls every pinned thread for new comments and state
+// changes. A thread seen for the first time is only recorded, so adding a
+// thread does not replay its history. Without notify the current state is
+// only remembered.
+func (c *MyPlugin) scanWatchedThreads(notify bool) {
+	for _, ref := range c.watchThreads {
+		key := ref.String()
+		issueURL := fmt.Sprintf("%s/repos/%s/%s/issues/%d", c.baseURL, ref.Owner, ref.Repo, ref.Number)
+		var issue threadIssue
+		if err := c.getJSON(issueURL, "application/vnd.github.v3+json", &issue); err != nil {
+			c.recordError(fmt.Sprintf("fetching watched thread %s", key), err)
+			continue
+		}
+
+		now := c.clock.Now()
+		state, known := c.threadStates[key]
+		if !known {
+			c.threadStates[key] = &threadState{State: issue.threadStateLabel(), Since: now, CheckedAt: now}
+			continue
+		}
+
+		if current := issue.threadStateLabel(); current != state.State {
+			state.State = current
+			if notify {
+				c.sendThreadMessage(fmt.Sprintf("%s was %s", key, current), issue.Title, issue.HTMLURL)
+			}
+		}
+
+		query := url.Values{"since": {state.CheckedAt.UTC().Format(time.RFC3339)}, "per_page": {"100"}}
+		comments, err := fetchAllPages[threadComment](c, issueURL+"/comments?"+query.Encode(), "application/vnd.github.v3+json")
+		if err != nil {
+			c.recordError(fmt.Sprintf("fetching comments of watched thread %s", key), err)
+			continue
+		}
+		state.CheckedAt = now
+		for _, comment := range comments {
+			// since also returns old comments that were edited recently.
+			key := strconv.FormatInt(comment.ID, 10)
+			if comment.CreatedAt.Before(state.Since) || c.seenThreadComments[key] {
+				continue
+			}
+			c.seenThreadComments[key] = true
+			if notify {
+				c.sendThreadMessage(fmt.Sprintf("New comment on %s", ref),
+					fmt.Sprintf("%s\n@%s: %s", issue.Title, comment.User.Login, snippet(comment.Body)), comment.HTMLURL)
+			}
+		}
+	}
+}
+
+func (c *MyPlugin) sendThreadMessage(title, message, clickURL string) {
+	msg := &plugin.Message{
+		Title:    title,
+		Message:  message,
+		Priority: c.watchThreadPriority,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": clickURL,
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending watched thread notification", err)
+	} else {
+		c.infoLog("sent watched thread notification", "title", title)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThreadURL(t *testing.T) {
+	for _, s := range []string{
+		"https://github.com/octocat/hello-world/pull/42",
+		"https://github.com/octocat/hello-world/pull/42/files",
+		"https://github.com/octocat/hello-world/issues/42",
+		"https://api.github.com/repos/octocat/hello-world/pulls/42",
+		"https://ghe.example.com/api/v3/repos/octocat/hello-world/issues/42",
+		"octocat/hello-world#42",
+	} {
+		ref, err := parseThreadURL(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, threadRef{Owner: "octocat", Repo: "hello-world", Number: 42}, ref, s)
+	}
+	for _, s := range []string{
+		"https://github.com/octocat/hello-world",
+		"https://github.com/octocat/hello-world/releases/42",
+		"https://github.com/octocat/hello-world/pull/abc",
+		"hello-world#42",
+		"not a url",
+	} {
+		_, err := parseThreadURL(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestWatchedThreadCommentsAndStateChanges(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.serveFixture("/notifications", "notifications_initial.json")
+	srv.handle("/repos/octocat/hello-world/issues/42", serveJSON(`{"title": "Fix the thing", "state": "open", "html_url": "https://github.com/octocat/hello-world/pull/42", "pull_request": {"merged_at": null}}`))
+	var since []string
+	comments := `[]`
+	srv.handle("/repos/octocat/hello-world/issues/42/comments", func(w http.ResponseWriter, r *http.Request) {
+		since = append(since, r.URL.Query().Get("since"))
+		serveJSON(comments)(w, r)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchThreads": "https://github.com/octocat/hello-world/pull/42"})
+	clk := newFakeClock()
+	p.clock = clk
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	assert.Empty(t, since, "the first scan only records the thread")
+
+	clk.Advance(time.Minute)
+	comments = `[
+		{"id": 1, "body": "old comment, edited", "html_url": "https://github.com/octocat/hello-world/pull/42#issuecomment-1", "created_at": "2024-05-01T08:00:00Z", "user": {"login": "alice"}},
+		{"id": 2, "body": "Looks good!", "html_url": "https://github.com/octocat/hello-world/pull/42#issuecomment-2", "created_at": "2024-05-01T12:00:30Z", "user": {"login": "bob"}}
+	]`
+	srv.handle("/repos/octocat/hello-world/issues/42", serveJSON(`{"title": "Fix the thing", "state": "closed", "html_url": "https://github.com/octocat/hello-world/pull/42", "pull_request": {"merged_at": "2024-05-01T12:00:40Z"}}`))
+	p.scanWatchedThreads(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "octocat/hello-world#42 was merged", msgs[0].Title)
+	assert.Equal(t, 6, msgs[0].Priority)
+	assert.Equal(t, "New comment on octocat/hello-world#42", msgs[1].Title)
+	assert.Equal(t, "Fix the thing\n@bob: Looks good!", msgs[1].Message)
+	assert.Equal(t, "https://github.com/octocat/hello-world/pull/42#issuecomment-2",
+		msgs[1].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+	assert.Equal(t, []string{"2024-05-01T12:00:00Z"}, since)
+
+	p.scanWatchedThreads(true)
+	assert.Len(t, rec.Messages(), 2, "comments and states are only reported once")
+}
+
+func TestWatchThreadsValidation(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "watchThreads": "https://github.com/octocat"}))
+	assert.Error(t, p.ValidateAndSetConfig(map[string]interface{}{"token": "x", "watchThreadPriority": 11}))
+}
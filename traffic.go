@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gotify/plugin-api"
+)
+
+type trafficDay struct {
+	Timestamp time.Time `json:"timestamp"`
+	Count     int       `json:"count"`
+	Uniques   int       `json:"uniques"`
+}
+
+// trafficSeries is the response of the traffic views and clones endpoints;
+// only the per-day breakdown is used.
+type trafficSeries struct {
+	Views  []trafficDay `json:"views"`
+	Clones []trafficDay `json:"clones"`
+}
+
+func trafficOn(days []trafficDay, day time.Time) trafficDay {
+	for _, d := range days {
+		if d.Timestamp.UTC().Format(time.DateOnly) == day.Format(time.DateOnly) {
+			return d
+		}
+	}
+	return trafficDay{}
+}
+
+// checkTrafficDigest sends the traffic summary once a day, after
+// trafficHour. It covers the last complete day; GitHub buckets traffic by
+// UTC day. The day of the last digest is persisted so restarts do not send
+// it twice.
+func (c *MyPlugin) checkTrafficDigest() {
+	now := c.clock.Now()
+	today := now.Format(time.DateOnly)
+	if now.Hour() < c.trafficHour || c.lastTrafficDigest == today {
+		return
+	}
+	day := now.UTC().Truncate(24 * time.Hour).Add(-24 * time.Hour)
+
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for traffic digest", err)
+		return
+	}
+	var lines []string
+	for _, repo := range repos {
+		if c.trafficForbidden[repo.FullName] {
+			continue
+		}
+		var views, clones trafficSeries
+		err := c.getJSON(fmt.Sprintf("%s/repos/%s/traffic/views?per=day", c.baseURL, repo.FullName), "application/vnd.github.v3+json", &views)
+		if err == nil {
+			err = c.getJSON(fmt.Sprintf("%s/repos/%s/traffic/clones?per=day", c.baseURL, repo.FullName), "application/vnd.github.v3+json", &clones)
+		}
+		if err != nil {
+			if classifyError(err).StatusCode == http.StatusForbidden {
+				c.infoLog("skipping traffic, the token needs push access", "repo", repo.FullName)
+				c.trafficForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching traffic of %s", repo.FullName), err)
+			}
+			continue
+		}
+		v, cl := trafficOn(views.Views, day), trafficOn(clones.Clones, day)
+		if v.Count == 0 && cl.Count == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: 👁 %d (%d unique) · ⬇ %d (%d unique)",
+			repo.FullName, v.Count, v.Uniques, cl.Count, cl.Uniques))
+	}
+
+	c.lastTrafficDigest = today
+	c.saveState()
+
+	message := "No views or clones."
+	if len(lines) > 0 {
+		message = strings.Join(lines, "\n")
+	}
+	msg := &plugin.Message{
+		Title:    fmt.Sprintf("Traffic on %s", day.Format(time.DateOnly)),
+		Message:  message,
+		Priority: 1,
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending traffic digest", err)
+	} else {
+		c.infoLog("sent traffic digest", "day", day.Format(time.DateOnly))
+	}
+}
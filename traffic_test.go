@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrafficDigest(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"},{"full_name":"octocat/forked"}]`))
+	srv.handle("/repos/octocat/hello-world/traffic/views", serveJSON(`{"count":200,"uniques":60,"views":[
+		{"timestamp":"2024-04-30T00:00:00Z","count":120,"uniques":45},
+		{"timestamp":"2024-05-01T00:00:00Z","count":80,"uniques":15}]}`))
+	srv.handle("/repos/octocat/hello-world/traffic/clones", serveJSON(`{"count":12,"uniques":5,"clones":[
+		{"timestamp":"2024-04-30T00:00:00Z","count":12,"uniques":5}]}`))
+	forbidden := 0
+	srv.handle("/repos/octocat/forked/traffic/views", func(w http.ResponseWriter, r *http.Request) {
+		forbidden++
+		http.Error(w, `{"message":"Must have push access to repository"}`, http.StatusForbidden)
+	})
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"trafficDigest": true, "trafficHour": 13})
+	clk := newFakeClock() // 2024-05-01 12:00 UTC
+	p.clock = clk
+	storage := &memoryStorage{}
+	p.SetStorageHandler(storage)
+	p.trafficForbidden = map[string]bool{}
+
+	p.checkTrafficDigest()
+	assert.Empty(t, rec.Messages(), "not before trafficHour")
+
+	clk.Advance(time.Hour)
+	p.checkTrafficDigest()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Traffic on 2024-04-30", msgs[0].Title)
+	assert.Equal(t, "octocat/hello-world: 👁 120 (45 unique) · ⬇ 12 (5 unique)", msgs[0].Message)
+	assert.Equal(t, "2024-05-01", p.loadState().LastTrafficDigest)
+
+	clk.Advance(time.Hour)
+	p.checkTrafficDigest()
+	assert.Len(t, rec.Messages(), 1, "sent once a day")
+
+	clk.Advance(24 * time.Hour)
+	p.checkTrafficDigest()
+	assert.Len(t, rec.Messages(), 2)
+	assert.Equal(t, 1, forbidden, "repos without access are skipped")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gotify/plugin-api"
+)
+
+// fetchUnreadCount asks for a single notification per page so that the page
+// number of the rel="last" link equals the number of unread notifications.
+func (c *MyPlugin) fetchUnreadCount() (int, error) {
+	req, err := c.newGitHubRequest(c.requestContext(), "GET", c.baseURL+"/notifications?per_page=1", "application/vnd.github.v3+json", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, responseError(resp)
+	}
+
+	if m := linkLastPageRe.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		last, err := url.Parse(m[1])
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(last.Query().Get("page"))
+	}
+
+	var notifications []GithubNotification
+	if err := json.NewDecoder(resp.Body).Decode(&notifications); err != nil {
+		return 0, err
+	}
+	return len(notifications), nil
+}
+
+func (c *MyPlugin) getUnreadCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unreadCount
+}
+
+func (c *MyPlugin) setUnreadCount(count int) (changed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	changed = c.unreadCount != count
+	c.unreadCount = count
+	return changed
+}
+
+func (c *MyPlugin) checkUnreadCount() {
+	if !c.readsNotifications() {
+		return
+	}
+	count, err := c.fetchUnreadCount()
+	if err != nil {
+		c.recordError("fetching unread notification count", err)
+		return
+	}
+	if !c.setUnreadCount(count) {
+		return
+	}
+
+	msg := &plugin.Message{
+		Title:    c.translate("unread.title"),
+		Message:  c.translate("unread.body", count),
+		Priority: 0,
+		Extras: map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click": map[string]interface{}{
+					"url": c.webURL("notifications"),
+				},
+			},
+		},
+	}
+	if err := c.msgHandler.SendMessage(*msg); err != nil {
+		c.recordError("sending unread count", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchUnreadCountReadsLastPage(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("per_page"))
+		w.Header().Set("Link", fmt.Sprintf(`<%s/notifications?per_page=1&page=2>; rel="next", <%s/notifications?per_page=1&page=42>; rel="last"`, srv.URL, srv.URL))
+		w.Write([]byte(`[]`))
+	})
+	p, _ := newTestPlugin(t, srv, nil)
+
+	count, err := p.fetchUnreadCount()
+	require.NoError(t, err)
+	assert.Equal(t, 42, count)
+
+	srv.handle("/notifications", serveJSON(`[`+notificationJSON("1", "Only one")+`]`))
+	count, err = p.fetchUnreadCount()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "without a Link header the single page is counted")
+}
+
+func TestCheckUnreadCountSendsOnlyOnChange(t *testing.T) {
+	var unread atomic.Int32
+	unread.Store(3)
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/notifications?per_page=1&page=%d>; rel="last"`, srv.URL, unread.Load()))
+		}
+		w.Write([]byte(`[]`))
+	})
+	p, rec := newTestPlugin(t, srv, nil)
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	p.checkUnreadCount()
+	p.checkUnreadCount()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "an unchanged count is not sent again")
+	assert.Equal(t, "3 unread notifications", msgs[0].Message)
+	assert.Equal(t, 3, p.getUnreadCount())
+
+	unread.Store(5)
+	p.checkUnreadCount()
+	msgs = rec.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "5 unread notifications", msgs[1].Message)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gotify/plugin-api"
+)
+
+// checkUnstars drops seen stars of repo that are missing from a freshly read
+// stargazer list and, with notifyUnstars set, reports them. A truncated list
+// proves nothing about the stars it did not reach. A paginated list may have
+// shifted between its pages, so a star has to be missing from two reads in a
+// row before it counts as removed.
+func (c *MyPlugin) checkUnstars(repo string, list stargazerList) {
+	if list.truncated {
+		return
+	}
+	current := make(map[string]bool, len(list.stars))
+	for _, star := range list.stars {
+		current[starKey(repo, star.User.Login)] = true
+	}
+
+	prefix := starKeyPrefix(repo)
+	var removed []string
+	for key := range c.seenStars {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if current[key] {
+			delete(c.unstarCandidates, key)
+			continue
+		}
+		if list.paginated && !c.unstarCandidates[key] {
+			c.unstarCandidates[key] = true
+			continue
+		}
+		delete(c.unstarCandidates, key)
+		delete(c.seenStars, key)
+		removed = append(removed, strings.TrimPrefix(key, prefix))
+	}
+	sort.Strings(removed)
+
+	for _, login := range removed {
+		c.infoLog("star removed", "login", login, "repo", repo)
+		if !c.notifyUnstars {
+			continue
+		}
+		msg := plugin.Message{
+			Title:    c.translate("unstar.title"),
+			Message:  c.translate("unstar.body", login, repo),
+			Priority: c.typePriority("star"),
+			Extras: map[string]interface{}{
+				"client::notification": map[string]interface{}{
+					"click": map[string]interface{}{
+						"url": c.webURL(repo),
+					},
+				},
+			},
+		}
+		if err := c.msgHandler.SendMessage(msg); err != nil {
+			c.recordError("sending unstar notification", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnstarIsReportedAndForgotten(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	stars := `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}},` +
+		`{"starred_at":"2024-04-02T09:00:00Z","user":{"login":"bob"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(stars)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "notifyUnstars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.True(t, p.seenStars.has("octocat/hello-world:alice"))
+
+	stars = `[{"starred_at":"2024-04-02T09:00:00Z","user":{"login":"bob"}}]`
+	p.checkStars()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Lost a Star", msgs[0].Title)
+	assert.Equal(t, "alice removed their star from octocat/hello-world", msgs[0].Message)
+	assert.False(t, p.seenStars.has("octocat/hello-world:alice"))
+	assert.True(t, p.seenStars.has("octocat/hello-world:bob"))
+
+	stars = `[{"starred_at":"2024-04-02T09:00:00Z","user":{"login":"bob"}},` +
+		`{"starred_at":"2024-06-01T09:00:00Z","user":{"login":"alice"}}]`
+	p.checkStars()
+	require.Len(t, rec.Messages(), 2)
+	assert.Equal(t, "Repo octocat/hello-world received a star from alice", rec.Messages()[1].Message, "a returning stargazer is a new star")
+}
+
+func TestUnstarWithoutNotificationOnlyForgets(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	stars := `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(stars)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	stars = `[]`
+	p.checkStars()
+
+	assert.Empty(t, rec.Messages())
+	assert.Empty(t, p.seenStars)
+}
+
+func TestUnstarOnPaginatedListNeedsTwoMisses(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	secondPage := `[{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"bob"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			serveJSON(secondPage)(w, r)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/octocat/hello-world/stargazers?page=2>; rel="next"`, srv.URL))
+		serveJSON(`[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "notifyUnstars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	secondPage = `[]`
+	p.checkStars()
+	assert.Empty(t, rec.Messages(), "a single miss on a paginated list may be a page shift")
+	assert.True(t, p.seenStars.has("octocat/hello-world:bob"))
+
+	p.checkStars()
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "bob removed their star from octocat/hello-world", msgs[0].Message)
+}
+
+func TestUnstarIgnoresTruncatedList(t *testing.T) {
+	p := &MyPlugin{
+		notifyUnstars:    true,
+		seenStars:        seenSet{"octocat/hello-world:alice": time.Now()},
+		unstarCandidates: map[string]bool{},
+	}
+	p.checkUnstars("octocat/hello-world", stargazerList{changed: true, paginated: true, truncated: true})
+	assert.True(t, p.seenStars.has("octocat/hello-world:alice"))
+}
+
+func TestDeletedRepoStarsAreForgotten(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/gone"},{"full_name":"octocat/hello-world"}]`))
+	gone := false
+	srv.handle("/repos/octocat/gone/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		if gone {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		serveJSON(`[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"alice"}}]`)(w, r)
+	})
+	stars := `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"bob"}}]`
+	srv.handle("/repos/octocat/hello-world/stargazers", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(stars)(w, r)
+	})
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchStars": true, "notifyUnstars": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	require.True(t, p.seenStars.has("octocat/gone:alice"))
+
+	gone = true
+	stars = `[{"starred_at":"2024-04-01T09:00:00Z","user":{"login":"bob"}},` +
+		`{"starred_at":"2024-05-01T09:00:00Z","user":{"login":"carol"}}]`
+	p.checkStars()
+
+	assert.False(t, p.seenStars.has("octocat/gone:alice"), "stars of a deleted repo are forgotten")
+	assert.True(t, p.seenStars.has("octocat/hello-world:carol"))
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "the other repos are still checked and a deleted repo is no unstar")
+	assert.Equal(t, "Repo octocat/hello-world received a star from carol", msgs[0].Message)
+	assert.Nil(t, p.lastError)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+)
+
+// authUser is the account the token belongs to.
+type authUser struct {
+	Login string `json:"login"`
+	ID    int64  `json:"id"`
+}
+
+// currentUser returns the authenticated user, fetching /user only when the
+// cached result is older than userCacheTTL. Every feature that needs the
+// token's login goes through here. Network and server errors are retried up
+// to userLookupRetries times; a rejected token is not.
+func (c *MyPlugin) currentUser() (authUser, error) {
+	c.mu.Lock()
+	user, fetchedAt := c.user, c.userFetchedAt
+	c.mu.Unlock()
+	if user.Login != "" && c.clock.Now().Sub(fetchedAt) < c.userCacheTTL {
+		return user, nil
+	}
+
+	var err error
+	for attempt := 0; attempt <= c.userLookupRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.userRetryDelay)
+		}
+		var fetched authUser
+		if err = c.getJSON(c.baseURL+"/user", "application/vnd.github.v3+json", &fetched); err == nil {
+			c.mu.Lock()
+			c.user = fetched
+			c.userFetchedAt = c.clock.Now()
+			c.mu.Unlock()
+			return fetched, nil
+		}
+		if fe := classifyError(err); fe.Kind != errorKindNetwork && fe.StatusCode < 500 {
+			break
+		}
+	}
+	return authUser{}, err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrentUserIsCached(t *testing.T) {
+	srv := newFixtureServer(t)
+	requests := 0
+	srv.handle("/user", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		serveJSON(`{"login":"octocat","id":583231}`)(w, r)
+	})
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"userCacheTTL": 60})
+	clk := newFakeClock()
+	p.clock = clk
+
+	user, err := p.currentUser()
+	require.NoError(t, err)
+	assert.Equal(t, authUser{Login: "octocat", ID: 583231}, user)
+	p.currentUser()
+	assert.Equal(t, 1, requests)
+
+	clk.Advance(time.Hour)
+	p.currentUser()
+	assert.Equal(t, 2, requests, "refreshed after the TTL")
+
+	p.handlePollError(&fetchError{Kind: errorKindAuth, StatusCode: http.StatusUnauthorized, Err: assert.AnError})
+	p.currentUser()
+	assert.Equal(t, 3, requests, "a rejected token invalidates the cache")
+}
+
+func TestCurrentUserRetries(t *testing.T) {
+	srv := newFixtureServer(t)
+	requests := 0
+	srv.handle("/user", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "oops", http.StatusBadGateway)
+			return
+		}
+		serveJSON(`{"login":"octocat","id":1}`)(w, r)
+	})
+	p, _ := newTestPlugin(t, srv, map[string]interface{}{"userLookupRetries": 2})
+	p.userRetryDelay = 0
+
+	user, err := p.currentUser()
+	require.NoError(t, err)
+	assert.Equal(t, "octocat", user.Login)
+	assert.Equal(t, 3, requests)
+
+	srv.handle("/user", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+	})
+	p.user = authUser{}
+	requests = 0
+	_, err = p.currentUser()
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests, "auth failures are not retried")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// vipActor returns the login of the person behind n when they are one of the
+// configured VIP actors, or "". The actor is the author of the latest
+// comment, falling back to the author of the issue or pull request.
+//
+// VIP notifications break through every filter: the repo, type and reason
+// filters, muted repos, unknown type suppression, the per-repo minimum gap,
+// digests and quiet hours. They are sent with at least vipPriority. Threads
+// snoozed by hand stay snoozed.
+func (c *MyPlugin) vipActor(n GithubNotification) string {
+	for _, endpoint := range []string{n.Subject.LatestCommentURL, n.Subject.URL} {
+		if endpoint == "" {
+			continue
+		}
+		var detail struct {
+			User struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		}
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &detail); err != nil {
+			c.recordError(fmt.Sprintf("resolving actor of notification %s", n.ID), err)
+			continue
+		}
+		if detail.User.Login == "" {
+			continue
+		}
+		if c.vipActors[strings.ToLower(detail.User.Login)] {
+			return detail.User.Login
+		}
+		return ""
+	}
+	return ""
+}
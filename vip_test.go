@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVIPActorBypassesFiltersAndBoostsPriority(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hello-world/issues/comments/1", serveJSON(`{"user":{"login":"TheBoss"}}`))
+	srv.handle("/repos/octocat/hello-world/issues/2", serveJSON(`{"user":{"login":"someone"}}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"vipActors": "theboss", "repoMinGap": 60})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+	p.repoLastSent["octocat/hello-world"] = time.Now()
+
+	srv.handle("/notifications", serveJSON(`[`+
+		`{"id":"1","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Ship it?","type":"Issue",`+
+		`"url":"`+srv.URL+`/repos/octocat/hello-world/issues/1","latest_comment_url":"`+srv.URL+`/repos/octocat/hello-world/issues/comments/1"}},`+
+		`{"id":"2","repository":{"full_name":"octocat/hello-world"},"subject":{"title":"Typo","type":"Issue",`+
+		`"url":"`+srv.URL+`/repos/octocat/hello-world/issues/2"}}]`))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1, "only the VIP breaks through the repo gap")
+	assert.Equal(t, "[Issue] Ship it?", msgs[0].Title)
+	assert.Equal(t, 8, msgs[0].Priority)
+	assert.Contains(t, msgs[0].Message, "from @TheBoss")
+}
+
+func TestVIPActorBreaksThroughFiltersAndMutedRepos(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/repos/octocat/hidden/issues/1", serveJSON(`{"user":{"login":"TheBoss"}}`))
+	srv.handle("/repos/octocat/noisy/issues/2", serveJSON(`{"user":{"login":"TheBoss"}}`))
+	srv.handle("/repos/octocat/noisy/issues/3", serveJSON(`{"user":{"login":"someone"}}`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{
+		"vipActors":      "theboss",
+		"excludeRepos":   "octocat/hidden",
+		"repoPriorities": map[string]interface{}{"octocat/noisy": -1},
+	})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	issue := func(id, repo, title string) string {
+		return `{"id":"` + id + `","repository":{"full_name":"` + repo + `"},"subject":{"title":"` + title + `","type":"Issue",` +
+			`"url":"` + srv.URL + `/repos/` + repo + `/issues/` + id + `"}}`
+	}
+	srv.handle("/notifications", serveJSON(`[`+issue("1", "octocat/hidden", "Excluded")+`,`+
+		issue("2", "octocat/noisy", "Muted")+`,`+issue("3", "octocat/noisy", "Quiet")+`]`))
+	p.checkNotifications()
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2, "the muted repo stays muted for everyone else")
+	assert.Equal(t, "[Issue] Excluded", msgs[0].Title)
+	assert.Equal(t, "[Issue] Muted", msgs[1].Title)
+	assert.Equal(t, 8, msgs[1].Priority)
+}
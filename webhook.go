@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gotify/plugin-api"
+)
+
+func (c *MyPlugin) RegisterWebhook(basePath string, mux *gin.RouterGroup) {
+	c.webhookBasePath = basePath
+	mux.GET("/status", c.handleStatus)
+	mux.GET("/unread", c.handleUnread)
+	mux.GET("/errors", c.handleErrors)
+	mux.GET("/snooze", c.handleListSnoozes)
+	mux.POST("/snooze", c.requireManagement, c.handleSnooze)
+	mux.POST("/read-all", c.requireManagement, c.handleReadAll)
+	mux.POST("/pause", c.requireManagement, c.handlePause)
+	mux.POST("/resume", c.requireManagement, c.handleResume)
+	mux.GET("/filters", c.handleGetFilters)
+	mux.POST("/filters", c.requireManagement, c.handleSetFilters)
+	mux.DELETE("/filters", c.requireManagement, c.handleResetFilters)
+	mux.POST("/github", c.handleGitHubEvent)
+	mux.POST("/test", c.requireManagement, c.handleTestMessage)
+}
+
+// handleTestMessage sends a sample message, so the delivery path can be
+// checked while setting the plugin up.
+func (c *MyPlugin) handleTestMessage(ctx *gin.Context) {
+	if c.msgHandler == nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "no message handler set"})
+		return
+	}
+	msg := plugin.Message{
+		Title:    "Test notification",
+		Message:  "GitHub notifications will arrive here.",
+		Priority: 2,
+	}
+	if err := c.msgHandler.SendMessage(msg); err != nil {
+		c.recordError("sending test notification", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": c.redactor.redact(err.Error())})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"sent": true})
+}
+
+// requireManagement guards endpoints that change state on GitHub, control
+// polling or send messages. They are only served when allowManagement is enabled and the
+// request carries the configured secret in the X-Webhook-Secret header.
+func (c *MyPlugin) requireManagement(ctx *gin.Context) {
+	allowManagement, webhookSecret, _ := c.webhookSecrets()
+	if !allowManagement {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "management endpoints are disabled"})
+		return
+	}
+	secret := ctx.GetHeader("X-Webhook-Secret")
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(webhookSecret)) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid webhook secret"})
+		return
+	}
+	ctx.Next()
+}
+
+// webhookSecrets returns the webhook settings, which a configuration change
+// may replace while a request is handled.
+func (c *MyPlugin) webhookSecrets() (allowManagement bool, webhookSecret, githubWebhookSecret string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.allowManagement, c.webhookSecret, c.githubWebhookSecret
+}
+
+func (c *MyPlugin) handleUnread(ctx *gin.Context) {
+	count := c.getUnreadCount()
+	if count < 0 {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "unread count not fetched yet"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+type statusResponse struct {
+	Enabled     bool         `json:"enabled"`
+	Login       string       `json:"login,omitempty"`
+	Paused      bool         `json:"paused"`
+	PausedAt    *time.Time   `json:"pausedAt,omitempty"`
+	PausedUntil *time.Time   `json:"pausedUntil,omitempty"`
+	OutageSince *time.Time   `json:"outageSince,omitempty"`
+	LastSuccess *time.Time   `json:"lastSuccess,omitempty"`
+	LastError   *errorStatus `json:"lastError,omitempty"`
+}
+
+func (c *MyPlugin) handleStatus(ctx *gin.Context) {
+	paused := c.isPaused()
+	c.mu.Lock()
+	status := statusResponse{
+		Enabled:   c.enabled,
+		Login:     c.user.Login,
+		Paused:    paused,
+		LastError: c.lastError,
+	}
+	if !c.pausedAt.IsZero() {
+		at := c.pausedAt
+		status.PausedAt = &at
+	} else if paused && !c.pausedForAuth {
+		until := c.pausedUntil
+		status.PausedUntil = &until
+	}
+	if !c.outageSince.IsZero() {
+		since := c.outageSince
+		status.OutageSince = &since
+		status.Paused = true
+	}
+	if !c.lastSuccess.IsZero() {
+		last := c.lastSuccess
+		status.LastSuccess = &last
+	}
+	c.mu.Unlock()
+	ctx.JSON(http.StatusOK, status)
+}
+
+func (c *MyPlugin) handleErrors(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.getRecentErrors())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestMessageWebhook(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret"})
+	require.NoError(t, p.Enable())
+	r := newWebhookRouter(p)
+	send := func(secret string) int {
+		req := httptest.NewRequest(http.MethodPost, "/test", nil)
+		req.Header.Set("X-Webhook-Secret", secret)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send(""))
+	assert.Empty(t, rec.Messages())
+	assert.Equal(t, http.StatusOK, send("s3cret"))
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Test notification", msgs[0].Title)
+
+	p.Disable()
+	assert.Equal(t, http.StatusInternalServerError, send("s3cret"), "a disabled plugin cannot send")
+	assert.Len(t, rec.Messages(), 1)
+}
+
+func TestTestMessageWebhookShownInDisplay(t *testing.T) {
+	srv := newFixtureServer(t)
+	p, _ := newTestPlugin(t, srv, nil)
+	newWebhookRouter(p)
+	assert.NotContains(t, p.GetDisplay(nil), "test`", "only shown when management endpoints are enabled")
+
+	p, _ = newTestPlugin(t, srv, map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret"})
+	newWebhookRouter(p)
+	assert.Contains(t, p.GetDisplay(nil), "`POST "+p.webhookBasePath+"test`")
+}
+
+func TestWebhookSecretsCanChangeWhileServing(t *testing.T) {
+	srv := newFixtureServer(t)
+	conf := map[string]interface{}{"allowManagement": true, "webhookSecret": "s3cret", "githubWebhookSecret": "gh-s3cret"}
+	p, rec := newTestPlugin(t, srv, conf)
+	r := newWebhookRouter(p)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			conf["token"] = "test-token"
+			conf["apiBaseURL"] = srv.URL
+			conf["allowManagement"] = i%2 == 0
+			assert.NoError(t, p.ValidateAndSetConfig(conf))
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		for _, path := range []string{"/test", "/github"} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+			assert.Contains(t, []int{http.StatusUnauthorized, http.StatusForbidden}, w.Code, path)
+		}
+	}
+	<-done
+	assert.Empty(t, rec.Messages())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gotify/plugin-api"
+)
+
+type wikiPage struct {
+	PageName string `json:"page_name"`
+	Title    string `json:"title"`
+	Action   string `json:"action"`
+	SHA      string `json:"sha"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type repoEvent struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Actor struct {
+		Login string `json:"login"`
+	} `json:"actor"`
+	Payload struct {
+		Pages []wikiPage `json:"pages"`
+	} `json:"payload"`
+}
+
+// scanWikiEdits looks for GollumEvents in the recent events of every repo
+// with a wiki enabled. Edits are keyed by repo, page and revision.
+func (c *MyPlugin) scanWikiEdits(notify bool) {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for wiki watch", err)
+		return
+	}
+
+	for _, repo := range repos {
+		if !repo.HasWiki {
+			continue
+		}
+		var events []repoEvent
+		endpoint := fmt.Sprintf("%s/repos/%s/events?per_page=100", c.baseURL, repo.FullName)
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &events); err != nil {
+			c.recordError(fmt.Sprintf("fetching events for %s", repo.FullName), err)
+			continue
+		}
+
+		// Events are newest first; notify oldest first.
+		for i := len(events) - 1; i >= 0; i-- {
+			event := events[i]
+			if event.Type != "GollumEvent" {
+				continue
+			}
+			for _, page := range event.Payload.Pages {
+				key := fmt.Sprintf("%s:%s:%s", repo.FullName, page.PageName, page.SHA)
+				if c.seenWikiEdits[key] {
+					continue
+				}
+				c.seenWikiEdits[key] = true
+				if !notify {
+					continue
+				}
+
+				msg := &plugin.Message{
+					Title:    fmt.Sprintf("Wiki page %s in %s", page.Action, repo.FullName),
+					Message:  fmt.Sprintf("%s %s \"%s\"", event.Actor.Login, page.Action, page.Title),
+					Priority: 2,
+					Extras: map[string]interface{}{
+						"client::notification": map[string]interface{}{
+							"click": map[string]interface{}{
+								"url": page.HTMLURL,
+							},
+						},
+					},
+				}
+				if err := c.msgHandler.SendMessage(*msg); err != nil {
+					c.recordError("sending wiki notification", err)
+				} else {
+					c.infoLog("sent wiki notification", "repo", repo.FullName, "page", page.PageName)
+				}
+			}
+		}
+	}
+}
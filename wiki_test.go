@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gollumEvent = `{"id":"%s","type":"GollumEvent","actor":{"login":"erin"},"payload":{"pages":[` +
+	`{"page_name":"Home","title":"Home","action":"edited","sha":"%s","html_url":"https://github.com/octocat/hello-world/wiki/Home"}]}}`
+
+func TestScanWikiEdits(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world","has_wiki":true},{"full_name":"octocat/no-wiki","has_wiki":false}]`))
+	srv.handle("/repos/octocat/hello-world/events", serveJSON(`[`+fmt.Sprintf(gollumEvent, "1", "aaa")+`]`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchWiki": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/repos/octocat/hello-world/events", serveJSON(`[`+
+		fmt.Sprintf(gollumEvent, "2", "bbb")+`,{"id":"3","type":"PushEvent"},`+fmt.Sprintf(gollumEvent, "1", "aaa")+`]`))
+	p.scanWikiEdits(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Wiki page edited in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, `erin edited "Home"`, msgs[0].Message)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gotify/plugin-api"
+)
+
+type workflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	HeadBranch string `json:"head_branch"`
+	RunNumber  int    `json:"run_number"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+// workflowFailures maps the conclusions that are reported to the phrase used
+// in the message title.
+var workflowFailures = map[string]string{
+	"failure":   "failed",
+	"cancelled": "was cancelled",
+	"timed_out": "timed out",
+}
+
+// scanWorkflowRuns looks for failed, cancelled or timed out GitHub Actions
+// runs among the recently completed runs of every watched repo. Runs are
+// keyed by repo and run ID. Repos whose runs the token cannot read are
+// skipped until the plugin is re-enabled.
+func (c *MyPlugin) scanWorkflowRuns(notify bool) {
+	repos, err := c.watchedRepos()
+	if err != nil {
+		c.recordError("fetching repos for workflow watch", err)
+		return
+	}
+
+	for _, repo := range repos {
+		if c.workflowRunsForbidden[repo.FullName] {
+			continue
+		}
+		var runs struct {
+			WorkflowRuns []workflowRun `json:"workflow_runs"`
+		}
+		endpoint := fmt.Sprintf("%s/repos/%s/actions/runs?status=completed&per_page=50", c.baseURL, repo.FullName)
+		if err := c.getJSON(endpoint, "application/vnd.github.v3+json", &runs); err != nil {
+			if status := classifyError(err).StatusCode; status == http.StatusForbidden || status == http.StatusNotFound {
+				c.infoLog("skipping workflow watch, the runs are not readable", "repo", repo.FullName)
+				c.workflowRunsForbidden[repo.FullName] = true
+			} else {
+				c.recordError(fmt.Sprintf("fetching workflow runs for %s", repo.FullName), err)
+			}
+			continue
+		}
+
+		// Runs are newest first; notify oldest first.
+		for i := len(runs.WorkflowRuns) - 1; i >= 0; i-- {
+			run := runs.WorkflowRuns[i]
+			outcome, failed := workflowFailures[run.Conclusion]
+			if !failed {
+				continue
+			}
+			key := fmt.Sprintf("%s:%d", repo.FullName, run.ID)
+			if c.seenWorkflowRuns[key] {
+				continue
+			}
+			c.seenWorkflowRuns[key] = true
+			if !notify {
+				continue
+			}
+
+			msg := &plugin.Message{
+				Title:    fmt.Sprintf("%s %s in %s", run.Name, outcome, repo.FullName),
+				Message:  fmt.Sprintf("Run #%d on %s", run.RunNumber, run.HeadBranch),
+				Priority: 5,
+				Extras: map[string]interface{}{
+					"client::notification": map[string]interface{}{
+						"click": map[string]interface{}{
+							"url": run.HTMLURL,
+						},
+					},
+				},
+			}
+			if err := c.msgHandler.SendMessage(*msg); err != nil {
+				c.recordError("sending workflow notification", err)
+			} else {
+				c.infoLog("sent workflow notification", "repo", repo.FullName, "run", run.ID)
+			}
+		}
+	}
+}
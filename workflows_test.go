@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func workflowRunJSON(id, conclusion string) string {
+	return `{"id":` + id + `,"name":"CI","head_branch":"main","run_number":` + id + `,"conclusion":"` + conclusion +
+		`","html_url":"https://github.com/octocat/hello-world/actions/runs/` + id + `"}`
+}
+
+func TestScanWorkflowRunsReportsOnlyFailures(t *testing.T) {
+	srv := newFixtureServer(t)
+	srv.handle("/notifications", serveJSON(`[]`))
+	srv.handle("/user/repos", serveJSON(`[{"full_name":"octocat/hello-world"}]`))
+	srv.handle("/repos/octocat/hello-world/actions/runs", serveJSON(`{"workflow_runs":[`+workflowRunJSON("1", "failure")+`]}`))
+
+	p, rec := newTestPlugin(t, srv, map[string]interface{}{"watchWorkflows": true})
+	require.NoError(t, p.Enable())
+	defer p.Disable()
+
+	srv.handle("/repos/octocat/hello-world/actions/runs", serveJSON(`{"workflow_runs":[`+
+		workflowRunJSON("5", "timed_out")+`,`+workflowRunJSON("4", "skipped")+`,`+
+		workflowRunJSON("3", "success")+`,`+workflowRunJSON("2", "failure")+`,`+workflowRunJSON("1", "failure")+`]}`))
+	p.scanWorkflowRuns(true)
+	p.scanWorkflowRuns(true)
+
+	msgs := rec.Messages()
+	require.Len(t, msgs, 2, "successful, skipped and already seen runs are not reported")
+	assert.Equal(t, "CI failed in octocat/hello-world", msgs[0].Title)
+	assert.Equal(t, "Run #2 on main", msgs[0].Message)
+	assert.Equal(t, "https://github.com/octocat/hello-world/actions/runs/2",
+		msgs[0].Extras["client::notification"].(map[string]interface{})["click"].(map[string]interface{})["url"])
+	assert.Equal(t, "CI timed out in octocat/hello-world", msgs[1].Title)
+}